@@ -1,83 +1,354 @@
 package check
 
 import (
-	"encoding/json"
 	"errors"
+	"fmt"
+	checkdiff "gh_foundations/cmd/check/diff"
+	checkhistory "gh_foundations/cmd/check/history"
+	checklist "gh_foundations/cmd/check/list"
+	"gh_foundations/cmd/exitcode"
+	"gh_foundations/cmd/githubclient"
+	"gh_foundations/internal/pkg/functions"
 	"gh_foundations/internal/pkg/types"
 	"gh_foundations/internal/pkg/types/github"
+	"gh_foundations/internal/pkg/types/history"
+	"gh_foundations/internal/pkg/types/policy"
+	"gh_foundations/internal/pkg/types/remediation"
+	"gh_foundations/internal/pkg/types/report"
 	"os"
-	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
-var outputFile = "check_results.json"
+const outputFileName = "check_results"
+
+var outputFormat string
+var outputPath string
+var policyFile string
+var includeChecks []string
+var skipChecks []string
+var failOn string
+var repoNames []string
+var repoTopics []string
+var repoVisibility string
+var managedProjectsDir string
+var historyPath string
+var remediateProjectsDir string
+var writeRemediation bool
+
+const (
+	failOnAny  = "any"
+	failOnNone = "none"
+)
 
 var CheckCmd = &cobra.Command{
-	Use:   "check",
+	Use:   "check <org-slug>[/<repo>]",
 	Short: "Perform checks against a Github configuration.",
-	Long:  `Perform checks against a Github configuration and generate reports.`,
+	Long: `Perform checks against a Github configuration and generate reports.
+
+The organization, its teams and all of its repositories are checked. To assess a single
+repository, pass <org-slug>/<repo>. The repositories can also be narrowed down with
+--repo, --topic and --visibility, in which case only the selected repositories are
+fetched and checked, without the organization and its teams. --only-managed limits the
+repositories to those managed by the Terragrunt configuration in a projects directory,
+along with the organization and its teams.
+
+Checks can be selected with --check and excluded with --skip. Both accept check ids
+(shell globs such as "secret_scanning*" are allowed), check types and guardrail ids
+such as "02". Run "check list" to see the available checks.
+
+The checks are defined by a policy file. The GoC guardrails policy is used unless
+another policy is given with --policy.
+
+The results are written to check_results.<format>, or to the path given with --output
+("-" writes to stdout). Use --format sarif to produce a SARIF 2.1.0 log that can be
+uploaded to GitHub code scanning. The table format is printed to stdout unless an
+output path is given, while the markdown and html formats produce reports with
+pass/fail counts per guardrail and per repository and the violations found.
+
+With --history, the run is also appended to check_history.jsonl, or to the file given
+as --history=<file>. Run "check history <org-slug>" to see when violations appeared or
+got fixed, and "check diff <old> <new>" to compare the results of two runs.
+
+An organization named after one of the subcommands, such as "list", is checked by
+giving its name after "--", e.g. "check --format sarif -- list".
+
+With --remediate <projects dir>, the changes to the repository sets of the Terragrunt
+configuration that fix the violations of managed repositories are printed as a diff,
+or written in place with --write. Repositories with violations that are not managed
+are listed as candidates for import.
+
+The command exits with code 0 when every check passed, 2 when violations were found
+and 1 when it failed, e.g. because the organization or its repositories could not be
+fetched. Use --fail-on to only fail on violations of checks with a minimum severity,
+or --fail-on none to never fail on violations. Checks that could not be evaluated, e.g.
+because the token is not allowed to read the data they need, are reported as errored
+and do not count as violations.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		p := policy.Default()
+		if policyFile != "" {
+			var err error
+			if p, err = policy.Load(policyFile); err != nil {
+				return err
+			}
+		}
+		return github.ApplyPolicy(p)
+	},
 	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) < 1 {
-			return errors.New("requires a GitHub organization slug")
+		if len(args) != 1 {
+			return errors.New("requires a GitHub organization slug or an <org-slug>/<repo> repository")
 		}
 		return nil
 	},
-	Run: func(cmd *cobra.Command, args []string) {
-		var err error
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := report.ParseFormat(outputFormat)
+		if err != nil {
+			return err
+		}
+		threshold, gated, err := parseFailOn(failOn)
+		if err != nil {
+			return err
+		}
+		if writeRemediation && remediateProjectsDir == "" {
+			return errors.New("--write requires --remediate")
+		}
+		cmd.SilenceUsage = true
+
+		slug, filter, err := resolveRepositoryFilter(args[0])
+		if err != nil {
+			return err
+		}
+
 		reports := make([]types.CheckReport, 0)
-		slug := args[0]
-		authToken, set := os.LookupEnv("GITHUB_TOKEN")
-		if !set {
-			authToken, err = getTokenFromGhCli()
+		selection := types.CheckSelection{Include: includeChecks, Exclude: skipChecks}
+		gs, err := githubclient.NewGithubService()
+		if err != nil {
+			return err
+		}
+
+		// Report on whatever could be fetched, but make sure fetch errors fail the command
+		var fetchErr error
+		checkOrg := checksOrganization(filter)
+		if checkOrg {
+			org, err := gs.GetOrganization(slug)
 			if err != nil {
-				cmd.PrintErr("GITHUB_TOKEN environment variable not set and unable to authenticate with gh cli")
-				return
+				fetchErr = errors.Join(fetchErr, fmt.Errorf("unable to fetch organization %s: %w", slug, err))
+			} else {
+				reports = append(reports, org.Check(selection))
 			}
 		}
 
-		gs := github.NewGithubService(authToken)
-		org, err := gs.GetOrganization(slug)
-		if err == nil {
-			reports = append(reports, org.Check([]types.CheckType{types.GoCGuardrails}))
+		// The repositories fetched before the deadline are returned along with the error
+		repos, err := gs.GetRepositories(slug, filter.Matches)
+		if err != nil {
+			fetchErr = errors.Join(fetchErr, fmt.Errorf("unable to fetch the repositories of %s: %w", slug, err))
+		}
+		for _, r := range repos {
+			reports = append(reports, r.Check(selection))
 		}
 
-		repos, err := gs.GetRepositories(slug, nil)
-		if err == nil {
-			for _, r := range repos {
-				reports = append(reports, r.Check([]types.CheckType{types.GoCGuardrails}))
+		if checkOrg {
+			teams, err := gs.GetTeams(slug)
+			if err != nil {
+				fetchErr = errors.Join(fetchErr, err)
 			}
+			for _, t := range teams {
+				reports = append(reports, t.Check(selection))
+			}
+		}
+
+		for _, u := range gs.GetQuotaUsage() {
+			cmd.PrintErrf("GitHub API %s quota: used %d requests (%d retried), %d/%d remaining until %s\n", u.Resource, u.Requests, u.Retries, u.Remaining, u.Limit, u.Reset.Format(time.RFC3339))
+		}
+
+		definitions := github.CheckDefinitions()
+		err = writeReport(cmd, format, slug, reports, definitions)
+		if historyPath != "" {
+			run := history.NewRun(time.Now().UTC().Format(time.RFC3339), slug, reports, definitions)
+			err = errors.Join(err, history.Append(historyPath, run))
+		}
+		if remediateProjectsDir != "" {
+			err = errors.Join(err, remediate(cmd, slug, reports))
 		}
+		if err != nil || fetchErr != nil {
+			return errors.Join(fetchErr, err)
+		}
+
+		summary := report.NewSummary(reports, definitions)
+		if summary.Errored > 0 {
+			cmd.PrintErrf("%d checks could not be evaluated, see the errors of the report\n", summary.Errored)
+		}
+		if gated {
+			if n := summary.CountViolations(threshold); n > 0 {
+				return &exitcode.Error{Code: exitcode.Violations, Err: fmt.Errorf("%d violations found (--fail-on %s)", n, failOn)}
+			}
+		}
+		return nil
+	},
+}
+
+func writeReport(cmd *cobra.Command, format report.Format, slug string, reports []types.CheckReport, definitions []types.CheckDefinition) error {
+	webURL, err := githubclient.WebURL()
+	if err != nil {
+		return err
+	}
 
-		file, err := os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY, 0644)
+	out := cmd.OutOrStdout()
+	if path := resolveOutputPath(format); path != "-" {
+		file, err := os.Create(path)
 		if err != nil {
-			cmd.PrintErr(err)
-			return
+			return err
 		}
 		defer file.Close()
+		out = file
+	}
 
-		bytes, err := json.Marshal(reports)
-		if err != nil {
-			cmd.PrintErr(err)
-			return
+	opts := report.Options{Definitions: definitions, WebURL: webURL, Organization: slug, Policy: policyFile}
+	return report.Write(out, format, reports, opts)
+}
+
+// Print or write the changes to the Terragrunt configuration that fix the violations
+func remediate(cmd *cobra.Command, slug string, reports []types.CheckReport) error {
+	orgSet, err := functions.FindManagedRepos(remediateProjectsDir)
+	if err != nil {
+		return fmt.Errorf("unable to read the managed repositories: %w", err)
+	}
+	plan, err := remediation.NewPlan(slug, reports, orgSet)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range plan.Unfixable {
+		if v.Reason != "" {
+			cmd.PrintErrf("No automatic fix for %s of repository %s: %s\n", v.CheckId, v.Repository, v.Reason)
+			continue
 		}
+		cmd.PrintErrf("No automatic fix for %s of repository %s\n", v.CheckId, v.Repository)
+	}
+	if len(plan.Unmanaged) > 0 {
+		cmd.PrintErrf("Repositories with violations that are not managed by the Terragrunt configuration, candidates for import:\n")
+		for _, name := range plan.Unmanaged {
+			cmd.PrintErrf("  %s/%s\n", slug, name)
+		}
+	}
 
-		file.Truncate(0)
-		file.Seek(0, 0)
-		file.Write(bytes)
-	},
+	if !writeRemediation {
+		return plan.WriteDiff(cmd.OutOrStdout())
+	}
+	if err := plan.Apply(); err != nil {
+		return err
+	}
+	for _, path := range plan.Paths() {
+		cmd.PrintErrf("Fixed %s\n", path)
+	}
+	return nil
+}
+
+func init() {
+	CheckCmd.PersistentFlags().StringVar(&policyFile, "policy", "", "Policy file defining the checks (defaults to the GoC guardrails policy)")
+	CheckCmd.Flags().StringVar(&outputFormat, "format", string(report.FormatJSON), fmt.Sprintf("Output format (%s)", report.FormatNames()))
+	CheckCmd.Flags().StringVarP(&outputPath, "output", "o", "", "File the results are written to, \"-\" for stdout (defaults to check_results.<format>)")
+	CheckCmd.Flags().StringVar(&failOn, "fail-on", failOnAny, "Exit with code 2 when checks fail: any, none, or the lowest severity that fails (low|medium|high|critical)")
+	CheckCmd.Flags().StringSliceVar(&repoNames, "repo", nil, "Only check the matching repositories (shell globs are allowed)")
+	CheckCmd.Flags().StringSliceVar(&repoTopics, "topic", nil, "Only check repositories with one of the topics")
+	CheckCmd.Flags().StringVar(&repoVisibility, "visibility", "", "Only check repositories with the visibility (public|private|internal)")
+	CheckCmd.Flags().StringVar(&managedProjectsDir, "only-managed", "", "Only check the repositories managed by the Terragrunt configuration in the projects directory")
+	CheckCmd.Flags().StringSliceVar(&includeChecks, "check", nil, "Only run the matching checks")
+	CheckCmd.Flags().StringSliceVar(&skipChecks, "skip", nil, "Skip the matching checks")
+	CheckCmd.Flags().StringVar(&remediateProjectsDir, "remediate", "", "Print the changes to the repository sets of the projects directory that fix the violations of managed repositories")
+	CheckCmd.Flags().BoolVar(&writeRemediation, "write", false, "With --remediate, write the changes to the repository sets instead of printing them")
+	CheckCmd.Flags().StringVar(&historyPath, "history", "", fmt.Sprintf("Append the results to the file, %s when no file is given, to follow them over time with \"check history\"", history.DefaultPath))
+	CheckCmd.Flags().Lookup("history").NoOptDefVal = history.DefaultPath
+
+	CheckCmd.AddCommand(checklist.ListCmd)
+	CheckCmd.AddCommand(checkhistory.HistoryCmd)
+	CheckCmd.AddCommand(checkdiff.DiffCmd)
+}
+
+// The table format is meant to be read in the terminal, the others are written to a file by default
+func resolveOutputPath(format report.Format) string {
+	if outputPath != "" {
+		return outputPath
+	}
+	if format == report.FormatTable {
+		return "-"
+	}
+	return fmt.Sprintf("%s.%s", outputFileName, format.Extension())
 }
 
-func getTokenFromGhCli() (string, error) {
-	cmd, set := os.LookupEnv("GH_PATH")
-	if !set {
-		cmd = "gh"
+// The --fail-on threshold is either any, none or a severity. Any is returned as an
+// empty severity, which counts every violation, and none disables the gate.
+func parseFailOn(value string) (types.Severity, bool, error) {
+	switch value {
+	case failOnAny:
+		return "", true, nil
+	case failOnNone:
+		return "", false, nil
 	}
-	out, err := exec.Command(cmd, "auth", "token").Output()
+	severity, err := types.ParseSeverity(value)
 	if err != nil {
-		return "", errors.New("unable to authenticate with gh cli")
+		return "", false, fmt.Errorf("invalid --fail-on: %w", err)
+	}
+	return severity, true, nil
+}
+
+// Build the repository filter from the flags and an optional /<repo> suffix of the organization slug
+func resolveRepositoryFilter(target string) (string, github.RepositoryFilter, error) {
+	filter := github.RepositoryFilter{
+		Names:      repoNames,
+		Topics:     repoTopics,
+		Visibility: repoVisibility,
+	}
+
+	slug, repo, found := strings.Cut(target, "/")
+	if slug == "" || (found && (repo == "" || strings.Contains(repo, "/"))) {
+		return "", filter, fmt.Errorf("invalid target %q, expected <org-slug> or <org-slug>/<repo>", target)
+	}
+	if found {
+		if len(repoNames) > 0 {
+			return "", filter, errors.New("--repo cannot be combined with an <org-slug>/<repo> target")
+		}
+		filter.Names = []string{repo}
+	}
+
+	if managedProjectsDir != "" {
+		managed, err := managedRepositories(managedProjectsDir, slug)
+		if err != nil {
+			return "", filter, err
+		}
+		filter.Managed = managed
+	}
+	return slug, filter, filter.Validate()
+}
+
+// The organization and its teams are checked along with all of its repositories, or the
+// managed ones, but not when selected repositories are assessed
+func checksOrganization(filter github.RepositoryFilter) bool {
+	return len(filter.Names) == 0 && len(filter.Topics) == 0 && filter.Visibility == ""
+}
+
+// The lower cased names of the organization's repositories found in the projects directory
+func managedRepositories(projectsDir string, slug string) (map[string]bool, error) {
+	orgSet, err := functions.FindManagedRepos(projectsDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the managed repositories: %w", err)
 	}
 
-	return strings.TrimSpace(string(out)), nil
+	managed := make(map[string]bool)
+	for org, projects := range orgSet.OrgProjectSets {
+		if !strings.EqualFold(org, slug) {
+			continue
+		}
+		for _, repoSet := range projects.RepositorySets {
+			for _, repo := range repoSet.PrivateRepositories {
+				managed[strings.ToLower(repo.Name)] = true
+			}
+			for _, repo := range repoSet.PublicRepositories {
+				managed[strings.ToLower(repo.Name)] = true
+			}
+		}
+	}
+	return managed, nil
 }
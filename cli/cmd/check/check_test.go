@@ -0,0 +1,68 @@
+package check
+
+import (
+	"bytes"
+	"gh_foundations/internal/pkg/types/github"
+	"gh_foundations/internal/pkg/types/report"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSingleRepository(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/orgs/octo-org/repos", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`[{"name": "app", "visibility": "private", "default_branch": "main"}, {"name": "docs", "visibility": "private", "default_branch": "main"}]`))
+	})
+	mux.HandleFunc("/api/v3/repos/octo-org/app/", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+	// Neither the organization, its teams nor the other repositories are fetched
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	t.Setenv("GITHUB_API_URL", server.URL+"/api/v3/")
+	t.Setenv("GITHUB_TOKEN", "token")
+
+	output := filepath.Join(t.TempDir(), "check_results.json")
+	CheckCmd.SetArgs([]string{"octo-org/app", "--output", output, "--fail-on", "none"})
+	CheckCmd.SetErr(&bytes.Buffer{})
+	require.NoError(t, CheckCmd.Execute())
+
+	reports, err := report.ReadReports(output)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, "github_repository", reports[0].EntityType)
+	assert.Equal(t, "app", reports[0].EntityId)
+}
+
+func TestCheckOrganizationNamedAfterSubcommand(t *testing.T) {
+	for _, name := range []string{"list", "history", "diff"} {
+		cmd, args, err := CheckCmd.Find([]string{name})
+		require.NoError(t, err)
+		assert.NotEqual(t, CheckCmd, cmd)
+
+		// After "--", the name is the organization to check
+		cmd, args, err = CheckCmd.Find([]string{"--", name})
+		require.NoError(t, err)
+		assert.Equal(t, CheckCmd, cmd)
+		require.NoError(t, cmd.ParseFlags(args))
+		assert.Equal(t, []string{name}, cmd.Flags().Args())
+		assert.NoError(t, cmd.ValidateArgs(cmd.Flags().Args()))
+	}
+}
+
+func TestChecksOrganization(t *testing.T) {
+	assert.True(t, checksOrganization(github.RepositoryFilter{}))
+	assert.True(t, checksOrganization(github.RepositoryFilter{Managed: map[string]bool{"app": true}}))
+	assert.False(t, checksOrganization(github.RepositoryFilter{Names: []string{"app"}}))
+	assert.False(t, checksOrganization(github.RepositoryFilter{Topics: []string{"payments"}}))
+	assert.False(t, checksOrganization(github.RepositoryFilter{Visibility: "public"}))
+}
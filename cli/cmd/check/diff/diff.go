@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"gh_foundations/cmd/exitcode"
+	"gh_foundations/internal/pkg/types/report"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var outputFormat string
+
+var DiffCmd = &cobra.Command{
+	Use:   "diff <old-results.json> <new-results.json>",
+	Short: "Compare two check results to show regressions and fixes.",
+	Long: `Compare two check results written with --format json and list, per organization,
+repository or team, the violations that are new, resolved or unchanged. Violations
+whose check was not run in the new results are listed as unchecked.
+
+The command exits with code 2 when the new results have violations the old ones do
+not, so scheduled jobs can act only when something gets worse.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if outputFormat != "table" && outputFormat != "json" {
+			return fmt.Errorf("unknown format %q, expected one of table|json", outputFormat)
+		}
+		oldReports, err := report.ReadReports(args[0])
+		if err != nil {
+			return err
+		}
+		newReports, err := report.ReadReports(args[1])
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+
+		diff := report.NewDiff(oldReports, newReports)
+		if outputFormat == "json" {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(diff)
+		} else {
+			err = writeTable(cmd.OutOrStdout(), diff)
+		}
+		if err != nil {
+			return err
+		}
+
+		if n := diff.Regressions(); n > 0 {
+			return &exitcode.Error{Code: exitcode.Violations, Err: fmt.Errorf("%d new violations found", n)}
+		}
+		return nil
+	},
+}
+
+func init() {
+	DiffCmd.Flags().StringVar(&outputFormat, "format", "table", "Output format (table|json)")
+}
+
+func writeTable(w io.Writer, diff report.Diff) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	var added, resolved, unchanged int
+	fmt.Fprintln(tw, "ENTITY\tTYPE\tCHANGE\tCHECK")
+	for _, e := range diff.Entities {
+		for _, change := range []struct {
+			name   string
+			checks []string
+		}{{"new", e.New}, {"resolved", e.Resolved}, {"unchanged", e.Unchanged}, {"unchecked", e.Unchecked}} {
+			for _, checkId := range change.checks {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.EntityId, e.EntityType, change.name, checkId)
+			}
+		}
+		added += len(e.New)
+		resolved += len(e.Resolved)
+		unchanged += len(e.Unchanged)
+	}
+	fmt.Fprintf(tw, "\n%d new, %d resolved and %d unchanged violations\n", added, resolved, unchanged)
+	return tw.Flush()
+}
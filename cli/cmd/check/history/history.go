@@ -0,0 +1,83 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"gh_foundations/internal/pkg/types/github"
+	"gh_foundations/internal/pkg/types/history"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var historyPath string
+var outputFormat string
+
+var HistoryCmd = &cobra.Command{
+	Use:   "history <org-slug>",
+	Short: "Show how the check results evolved over time.",
+	Long: `Show how the check results of an organization evolved over the runs recorded in the
+check history: the checks passed and failed by every run, and when each violation
+appeared or got fixed, per guardrail and per organization, repository or team.
+
+A violation is fixed by the first run where its check passes again. Runs limited to
+some repositories or checks leave the violations of the other ones unchanged.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if outputFormat != "table" && outputFormat != "json" {
+			return fmt.Errorf("unknown format %q, expected one of table|json", outputFormat)
+		}
+		runs, err := history.Load(historyPath, args[0])
+		if err != nil {
+			return err
+		}
+		if len(runs) == 0 {
+			return fmt.Errorf("no runs of %s in the check history %s", args[0], historyPath)
+		}
+
+		trend := history.NewTrend(runs, github.CheckDefinitions())
+		if outputFormat == "json" {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(trend)
+		}
+		return writeTable(cmd.OutOrStdout(), trend)
+	},
+}
+
+func init() {
+	HistoryCmd.Flags().StringVar(&historyPath, "history", history.DefaultPath, "The check history file")
+	HistoryCmd.Flags().StringVar(&outputFormat, "format", "table", "Output format (table|json)")
+}
+
+func writeTable(w io.Writer, trend history.Trend) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "RUN\tPASSED\tFAILED")
+	for _, r := range trend.Runs {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", r.Timestamp, r.Passed, r.Failed)
+	}
+
+	fmt.Fprintln(tw, "\nGUARDRAIL\tRUN\tCHANGE\tENTITY\tCHECK")
+	for _, g := range trend.Guardrails() {
+		for _, e := range trend.GuardrailEvents(g) {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", g, e.Timestamp, e.Change, e.EntityId, e.CheckId)
+		}
+	}
+
+	// Group the events of every entity, keeping them in chronological order
+	events := append([]history.Event{}, trend.Events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].EntityType != events[j].EntityType {
+			return events[i].EntityType < events[j].EntityType
+		}
+		return events[i].EntityId < events[j].EntityId
+	})
+	fmt.Fprintln(tw, "\nENTITY\tTYPE\tRUN\tCHANGE\tCHECK")
+	for _, e := range events {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.EntityId, e.EntityType, e.Timestamp, e.Change, e.CheckId)
+	}
+	return tw.Flush()
+}
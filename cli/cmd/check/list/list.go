@@ -0,0 +1,34 @@
+package list
+
+import (
+	"fmt"
+	"gh_foundations/internal/pkg/types"
+	"gh_foundations/internal/pkg/types/github"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var ListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the available checks.",
+	Long:  `List every check that can be run by the check command, with the guardrails it maps to and its severity.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tENTITY\tTYPE\tSEVERITY\tGUARDRAILS\tTITLE")
+		for _, def := range github.CheckDefinitions() {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", def.Id, def.EntityType, def.Type, def.Severity, joinGuardrails(def.Guardrails), def.Title)
+		}
+		w.Flush()
+	},
+}
+
+func joinGuardrails(guardrails []types.Guardrail) string {
+	ids := make([]string, len(guardrails))
+	for i, g := range guardrails {
+		ids[i] = string(g)
+	}
+	return strings.Join(ids, ",")
+}
@@ -0,0 +1,111 @@
+package drift
+
+import (
+	"errors"
+	"fmt"
+	"gh_foundations/cmd/exitcode"
+	"gh_foundations/cmd/githubclient"
+	"gh_foundations/internal/pkg/functions"
+	"gh_foundations/internal/pkg/types/drift"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var outputFormat string
+var outputPath string
+var orgs []string
+
+var DriftCmd = &cobra.Command{
+	Use:   "drift <projects dir>",
+	Short: "Compare the live repository settings with the Terragrunt configuration.",
+	Long: `Compare the settings of the repositories managed by the Terragrunt configuration in the
+projects directory with their live settings on GitHub, without running terragrunt plan.
+
+The description, homepage, topics, default branch, visibility, merge settings and
+security settings of every managed repository are compared with its inputs. Inputs the
+repository set does not give are left to the module defaults and are not compared.
+Managed repositories that do not exist, and repositories of the organizations that are
+not managed, are reported too. Settings the token cannot read are not compared.
+
+Every organization of the projects directory is compared, or only the ones given with
+--org. The report is written as Markdown or, with --format json, as JSON.
+
+The command exits with code 2 when drift is found, like terraform plan -detailed-exitcode.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if outputFormat != "markdown" && outputFormat != "json" {
+			return fmt.Errorf("unknown format %q, expected one of markdown|json", outputFormat)
+		}
+		cmd.SilenceUsage = true
+
+		managed, err := functions.FindManagedRepos(args[0])
+		if err != nil {
+			return fmt.Errorf("unable to read the managed repositories: %w", err)
+		}
+		gs, err := githubclient.NewGithubService()
+		if err != nil {
+			return err
+		}
+
+		var slugs []string
+		for org := range managed.OrgProjectSets {
+			if len(orgs) == 0 || containsFold(orgs, org) {
+				slugs = append(slugs, org)
+			}
+		}
+		sort.Strings(slugs)
+
+		// Report on the organizations that could be fetched, but fail on the others
+		var fetchErr error
+		report := drift.Report{Repositories: []drift.RepositoryDrift{}}
+		for _, slug := range slugs {
+			live, err := gs.ListRepositories(slug)
+			if err != nil {
+				fetchErr = errors.Join(fetchErr, fmt.Errorf("unable to fetch the repositories of %s: %w", slug, err))
+				continue
+			}
+			report.Append(drift.Compare(slug, managed, live))
+		}
+
+		out := cmd.OutOrStdout()
+		if outputPath != "-" {
+			file, err := os.Create(outputPath)
+			if err != nil {
+				return errors.Join(fetchErr, err)
+			}
+			defer file.Close()
+			out = file
+		}
+		if outputFormat == "json" {
+			err = report.WriteJSON(out)
+		} else {
+			err = report.WriteMarkdown(out)
+		}
+		if err != nil || fetchErr != nil {
+			return errors.Join(fetchErr, err)
+		}
+
+		if n := len(report.Repositories); n > 0 {
+			return &exitcode.Error{Code: exitcode.Violations, Err: fmt.Errorf("%d repositories drifted from the configuration", n)}
+		}
+		return nil
+	},
+}
+
+func init() {
+	DriftCmd.Flags().StringVar(&outputFormat, "format", "markdown", "Output format (markdown|json)")
+	DriftCmd.Flags().StringVarP(&outputPath, "output", "o", "-", "File the report is written to, \"-\" for stdout")
+	DriftCmd.Flags().StringSliceVar(&orgs, "org", nil, "Only compare the repositories of the organizations")
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
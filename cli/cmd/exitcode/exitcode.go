@@ -0,0 +1,38 @@
+package exitcode
+
+import "errors"
+
+// The exit codes of the CLI, following terraform plan -detailed-exitcode
+const (
+	Success = 0
+	// The command failed, e.g. because data could not be fetched
+	Failure = 1
+	// The command ran but found violations, e.g. failed checks
+	Violations = 2
+)
+
+// Error is returned by commands that exit with a specific code
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// The exit code for an error returned by a command
+func For(err error) int {
+	if err == nil {
+		return Success
+	}
+	var exitErr *Error
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return Failure
+}
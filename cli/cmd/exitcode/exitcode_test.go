@@ -0,0 +1,19 @@
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFor(t *testing.T) {
+	violations := &Error{Code: Violations, Err: errors.New("3 violations found")}
+
+	assert.Equal(t, Success, For(nil))
+	assert.Equal(t, Failure, For(errors.New("unable to fetch organization")))
+	assert.Equal(t, Violations, For(violations))
+	assert.Equal(t, Violations, For(fmt.Errorf("check: %w", violations)))
+	assert.Equal(t, "3 violations found", violations.Error())
+}
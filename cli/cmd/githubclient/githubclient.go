@@ -0,0 +1,125 @@
+package githubclient
+
+import (
+	"errors"
+	"fmt"
+	"gh_foundations/internal/pkg/types/github"
+	"os"
+	"strconv"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// The GitHub settings are kept apart from the global viper instance, which is used to read HCL files
+var settings = viper.New()
+
+func init() {
+	// Settings can be given as GITHUB_<KEY> environment variables, e.g. GITHUB_API_URL
+	settings.SetEnvPrefix("github")
+	settings.AutomaticEnv()
+}
+
+// Register the GitHub flags shared by every command that calls the GitHub API
+func AddFlags(flags *pflag.FlagSet) {
+	flags.String("api-url", "", "GitHub API URL, e.g. https://ghes.example.com/api/v3 (env GITHUB_API_URL)")
+	flags.String("upload-url", "", "GitHub upload URL, derived from the API URL when not set (env GITHUB_UPLOAD_URL)")
+	flags.String("app-id", "", "GitHub App ID to authenticate as (env GITHUB_APP_ID)")
+	flags.String("app-installation-id", "", "GitHub App installation ID (env GITHUB_APP_INSTALLATION_ID)")
+	flags.String("app-private-key", "", "Path to the GitHub App private key file (env GITHUB_APP_PRIVATE_KEY_PATH)")
+
+	settings.BindPFlag("api_url", flags.Lookup("api-url"))
+	settings.BindPFlag("upload_url", flags.Lookup("upload-url"))
+	settings.BindPFlag("app_id", flags.Lookup("app-id"))
+	settings.BindPFlag("app_installation_id", flags.Lookup("app-installation-id"))
+	settings.BindPFlag("app_private_key_path", flags.Lookup("app-private-key"))
+}
+
+// Read the GitHub settings from a YAML config file. When no path is given the
+// optional $HOME/.gh_foundations.yaml file is used.
+func LoadConfig(path string) error {
+	if path != "" {
+		settings.SetConfigFile(path)
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		settings.AddConfigPath(home)
+		settings.SetConfigName(".gh_foundations")
+		settings.SetConfigType("yaml")
+	}
+
+	if err := settings.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if path == "" && errors.As(err, &notFound) {
+			return nil
+		}
+		return fmt.Errorf("unable to read config file: %w", err)
+	}
+	return nil
+}
+
+// Create a GitHub service for the configured host, authenticated with, in order of
+// precedence, GitHub App credentials, the GITHUB_TOKEN environment variable or the gh cli.
+func NewGithubService() (github.IGithubService, error) {
+	opts, err := resolveAuthOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := github.NewCredentialProvider(opts)
+	if err != nil {
+		return nil, err
+	}
+	return github.NewGithubService(credentials, opts.Endpoint)
+}
+
+// The API endpoint comes from the settings, or from the host the gh cli is pointed at
+func resolveEndpoint() github.Endpoint {
+	if apiURL := settings.GetString("api_url"); apiURL != "" {
+		return github.Endpoint{
+			BaseURL:   apiURL,
+			UploadURL: settings.GetString("upload_url"),
+		}
+	}
+	return github.EndpointForHost(os.Getenv("GH_HOST"))
+}
+
+func resolveAuthOptions() (github.AuthOptions, error) {
+	opts := github.AuthOptions{
+		Token:             os.Getenv("GITHUB_TOKEN"),
+		AppPrivateKeyPath: settings.GetString("app_private_key_path"),
+		Endpoint:          resolveEndpoint(),
+	}
+
+	var err error
+	if opts.AppId, err = getInt64Setting("app_id"); err != nil {
+		return opts, err
+	}
+	if opts.AppInstallationId, err = getInt64Setting("app_installation_id"); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+func getInt64Setting(key string) (int64, error) {
+	value := settings.GetString(key)
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number: %w", key, err)
+	}
+	return id, nil
+}
+
+// The web URL of the configured GitHub host, e.g. https://github.com/
+func WebURL() (string, error) {
+	host, err := resolveEndpoint().Host()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://%s/", host), nil
+}
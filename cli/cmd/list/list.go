@@ -2,7 +2,9 @@ package list
 
 import (
 	orgs "gh_foundations/cmd/list/orgs"
+	"gh_foundations/cmd/list/output"
 	repos "gh_foundations/cmd/list/repos"
+	teams "gh_foundations/cmd/list/teams"
 
 	"github.com/spf13/cobra"
 )
@@ -14,11 +16,19 @@ var ListCmd = &cobra.Command{
 	Currently supported resources are:\n\n
 
 	- repos\n
-	- orgs\n\n`,
+	- orgs\n
+	- teams\n\n
+	The resources are written to stdout as json, or in the format given with --output,
+	and logs to stderr.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return output.Setup(cmd)
+	},
 }
 
 func init() {
 	ListCmd.AddCommand(orgs.OrgsCmd)
 	ListCmd.AddCommand(repos.ReposCmd)
+	ListCmd.AddCommand(teams.TeamsCmd)
 
+	output.AddFlags(ListCmd)
 }
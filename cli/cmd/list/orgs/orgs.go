@@ -6,43 +6,59 @@ package list
 import (
 	"errors"
 	"fmt"
+	"gh_foundations/cmd/list/output"
 	"gh_foundations/internal/pkg/functions"
-	"os"
-	"strings"
+	"gh_foundations/internal/pkg/types/terragrunt"
 
 	"github.com/spf13/cobra"
 )
 
+var keepGoing bool
+
 var OrgsCmd = &cobra.Command{
 	Use:   "orgs",
 	Short: "List managed organizations's slugs.",
-	Long: `This command reads the "providers.hcl" files in the "providers" directory and lists the organization slugs that are managed by the tool.`,
+	Long: `This command reads the "providers.hcl" files in the "providers" directory and lists the organization slugs that are managed by the tool.
+
+The command fails when a "providers.hcl" file cannot be read. With --keep-going, the errors
+are printed to stderr and the organizations of the other files are listed.`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
 			return errors.New("requires the path of the \"providers\" directory")
 		}
 		return nil
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
 
 		orgsDir := args[0]
 
 		orgs, err := functions.FindManagedOrgSlugs(orgsDir)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+		var configErrs terragrunt.ConfigErrors
+		if err != nil && keepGoing && errors.As(err, &configErrs) {
+			for _, configErr := range configErrs {
+				cmd.PrintErrf("Skipping %s\n", configErr)
+			}
+		} else if err != nil {
+			return fmt.Errorf("unable to read the managed organizations: %w", err)
 		}
 
-		var orgOut string = "[]"
-
-		if len(orgs) > 0 {
-			orgOut = fmt.Sprintf("['%s']", strings.Join(orgs, "', '"))
+		records := make([]organization, 0, len(orgs))
+		for _, org := range orgs {
+			records = append(records, organization{Org: org})
 		}
-
-		fmt.Println(orgOut)
-
+		return output.Write(cmd.OutOrStdout(), records, columns)
 	},
 }
 
 func init() {
+	OrgsCmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Report the providers.hcl files that cannot be read and list the organizations of the others")
+}
+
+type organization struct {
+	Org string `json:"org" yaml:"org"`
+}
+
+var columns = []output.Column[organization]{
+	{Header: "org", Value: func(o organization) string { return o.Org }},
 }
@@ -0,0 +1,129 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	JSON  = "json"
+	YAML  = "yaml"
+	CSV   = "csv"
+	Table = "table"
+	// A strategy matrix of the records, for fromJson in a GitHub Actions workflow
+	GitHubMatrix = "github-matrix"
+)
+
+var Formats = []string{JSON, YAML, CSV, Table, GitHubMatrix}
+
+var format string
+var quiet bool
+var verbose bool
+
+// AddFlags adds the --output, --quiet and --verbose flags to the list command and its
+// subcommands
+func AddFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&format, "output", "o", JSON, fmt.Sprintf("Output format (%s)", strings.Join(Formats, "|")))
+	flags.BoolVarP(&quiet, "quiet", "q", false, "Only log errors to stderr")
+	flags.BoolVarP(&verbose, "verbose", "v", false, "Log every file read to stderr")
+	cmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+}
+
+// Setup validates the output format and sends the logs to stderr, so only the records are
+// written to stdout. The files read are only logged with --verbose.
+func Setup(cmd *cobra.Command) error {
+	if !slices.Contains(Formats, format) {
+		return fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(Formats, "|"))
+	}
+	log.SetOutput(io.Discard)
+	if verbose {
+		log.SetOutput(cmd.ErrOrStderr())
+	}
+	return nil
+}
+
+// Infof logs a message to stderr, unless --quiet is set
+func Infof(cmd *cobra.Command, message string, args ...any) {
+	if !quiet {
+		cmd.PrintErrf(message+"\n", args...)
+	}
+}
+
+// Column is a column of the csv and table formats
+type Column[T any] struct {
+	Header string
+	Value  func(record T) string
+}
+
+// Write writes the records in the format given with --output. The json, yaml and
+// github-matrix formats encode the records themselves, the csv and table formats their
+// columns.
+func Write[T any](w io.Writer, records []T, columns []Column[T]) error {
+	if records == nil {
+		records = []T{}
+	}
+
+	switch format {
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case YAML:
+		out, err := yaml.Marshal(records)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	case GitHubMatrix:
+		// On a single line, to be written to $GITHUB_OUTPUT
+		return json.NewEncoder(w).Encode(map[string][]T{"include": records})
+	case CSV:
+		writer := csv.NewWriter(w)
+		headers := make([]string, len(columns))
+		for i, column := range columns {
+			headers[i] = column.Header
+		}
+		if err := writer.Write(headers); err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := writer.Write(row(record, columns)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case Table:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		headers := make([]string, len(columns))
+		for i, column := range columns {
+			headers[i] = strings.ToUpper(column.Header)
+		}
+		fmt.Fprintln(tw, strings.Join(headers, "\t"))
+		for _, record := range records {
+			fmt.Fprintln(tw, strings.Join(row(record, columns), "\t"))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(Formats, "|"))
+	}
+}
+
+func row[T any](record T, columns []Column[T]) []string {
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		values[i] = column.Value(record)
+	}
+	return values
+}
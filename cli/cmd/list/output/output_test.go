@@ -0,0 +1,87 @@
+package output
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type record struct {
+	Name   string   `json:"name" yaml:"name"`
+	Topics []string `json:"topics" yaml:"topics"`
+	GHAS   bool     `json:"ghas" yaml:"ghas"`
+}
+
+var records = []record{
+	{Name: "app", Topics: []string{"go", "api"}, GHAS: true},
+	{Name: "docs", Topics: []string{}},
+}
+
+var columns = []Column[record]{
+	{Header: "name", Value: func(r record) string { return r.Name }},
+	{Header: "topics", Value: func(r record) string { return strings.Join(r.Topics, ",") }},
+	{Header: "ghas", Value: func(r record) string { return strconv.FormatBool(r.GHAS) }},
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{JSON, `[
+  {
+    "name": "app",
+    "topics": [
+      "go",
+      "api"
+    ],
+    "ghas": true
+  },
+  {
+    "name": "docs",
+    "topics": [],
+    "ghas": false
+  }
+]
+`},
+		{YAML, `- name: app
+  topics:
+  - go
+  - api
+  ghas: true
+- name: docs
+  topics: []
+  ghas: false
+`},
+		{CSV, "name,topics,ghas\napp,\"go,api\",true\ndocs,,false\n"},
+		{Table, "NAME  TOPICS  GHAS\napp   go,api  true\ndocs          false\n"},
+		{GitHubMatrix, `{"include":[{"name":"app","topics":["go","api"],"ghas":true},{"name":"docs","topics":[],"ghas":false}]}` + "\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			format = test.format
+			defer func() { format = JSON }()
+
+			var out bytes.Buffer
+			require.NoError(t, Write(&out, records, columns))
+			assert.Equal(t, test.expected, out.String())
+		})
+	}
+}
+
+func TestWriteNoRecords(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, Write[record](&out, nil, columns))
+	assert.Equal(t, "[]\n", out.String())
+
+	format = GitHubMatrix
+	defer func() { format = JSON }()
+	out.Reset()
+	require.NoError(t, Write[record](&out, nil, columns))
+	assert.Equal(t, `{"include":[]}`+"\n", out.String())
+}
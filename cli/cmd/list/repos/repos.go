@@ -6,69 +6,132 @@ package list
 import (
 	"errors"
 	"fmt"
+	"gh_foundations/cmd/list/output"
 	"gh_foundations/internal/pkg/functions"
 	"gh_foundations/internal/pkg/types/status"
-	"log"
+	"gh_foundations/internal/pkg/types/terragrunt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var ghas bool
+var keepGoing bool
 
 var ReposCmd = &cobra.Command{
 	Use:   "repos",
 	Short: "List managed repositories.",
-	Long: `List managed repositories. This command will list all repositories.`,
+	Long: `List managed repositories. This command will list all repositories, with their
+organization, project, visibility, whether GHAS is enabled and the repository set they
+are defined in.
+
+The command fails when a repository set cannot be read. With --keep-going, the errors
+are printed to stderr and the repositories of the other files are listed.`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
 			return errors.New("requires the path of the \"projects\" directory")
 		}
 		return nil
 	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
 
 		reposDir := args[0]
 
 		orgSet, err := functions.FindManagedRepos(reposDir)
-		if err != nil {
-			log.Fatalf("Error in findManagedRepos: %s", err)
+		var configErrs terragrunt.ConfigErrors
+		if err != nil && keepGoing && errors.As(err, &configErrs) {
+			for _, configErr := range configErrs {
+				cmd.PrintErrf("Skipping %s\n", configErr)
+			}
+		} else if err != nil {
+			return fmt.Errorf("unable to read the managed repositories: %w", err)
 		}
 
-		repoList := flattenRepos(orgSet)
-
 		if ghas {
 			orgSet = orgSet.WithGHASEnabled()
-			repoList = flattenRepos(orgSet)
-			log.Printf("Found %d repositories with GHAS enabled\n", len(repoList))
 		}
-
-		repos := "[]"
-		if len(repoList) > 0 {
-			repos = fmt.Sprintf("['%s']", strings.Join(repoList, "', '"))
+		repos := flattenRepos(orgSet)
+		if ghas {
+			output.Infof(cmd, "Found %d repositories with GHAS enabled", len(repos))
 		}
 
-		fmt.Println(repos)
+		return output.Write(cmd.OutOrStdout(), repos, columns)
 	},
 }
 
 func init() {
 	ReposCmd.Flags().BoolVarP(&ghas, "ghas", "g", false, "List repositories with GHAS enabled")
+	ReposCmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Report the repository sets that cannot be read and list the repositories of the others")
+}
+
+type repository struct {
+	Org     string `json:"org" yaml:"org"`
+	Project string `json:"project" yaml:"project"`
+	Name    string `json:"name" yaml:"name"`
+	// <org>/<name>, as used by actions/checkout
+	FullName   string `json:"full_name" yaml:"full_name"`
+	Visibility string `json:"visibility" yaml:"visibility"`
+	// GHAS is always enabled on public repositories
+	GHAS bool `json:"ghas" yaml:"ghas"`
+	// The repository set the repository is defined in
+	SourceFile string `json:"source_file" yaml:"source_file"`
+}
+
+var columns = []output.Column[repository]{
+	{Header: "org", Value: func(r repository) string { return r.Org }},
+	{Header: "project", Value: func(r repository) string { return r.Project }},
+	{Header: "name", Value: func(r repository) string { return r.Name }},
+	{Header: "full_name", Value: func(r repository) string { return r.FullName }},
+	{Header: "visibility", Value: func(r repository) string { return r.Visibility }},
+	{Header: "ghas", Value: func(r repository) string { return strconv.FormatBool(r.GHAS) }},
+	{Header: "source_file", Value: func(r repository) string { return r.SourceFile }},
 }
 
-// Return only the names of the repositories managed by the tool
-func flattenRepos(org status.OrgSet) []string {
-	var repoNames []string
+// Return the records of the repositories managed by the tool, sorted by organization,
+// project and name
+func flattenRepos(org status.OrgSet) []repository {
+	var repos []repository
 
 	for org, projects := range org.OrgProjectSets {
-		for _, repoSet := range projects.RepositorySets {
+		for project, repoSet := range projects.RepositorySets {
+			sourceFile := projects.RepositorySetPaths[project]
 			for _, repo := range repoSet.PrivateRepositories {
-				repoNames = append(repoNames, fmt.Sprintf("%s/%s", org, repo.Name))
+				repos = append(repos, repository{
+					Org:        org,
+					Project:    project,
+					Name:       repo.Name,
+					FullName:   fmt.Sprintf("%s/%s", org, repo.Name),
+					Visibility: "private",
+					GHAS:       repo.AdvanceSecurity,
+					SourceFile: sourceFile,
+				})
 			}
 			for _, repo := range repoSet.PublicRepositories {
-				repoNames = append(repoNames, fmt.Sprintf("%s/%s", org, repo.Name))
+				repos = append(repos, repository{
+					Org:        org,
+					Project:    project,
+					Name:       repo.Name,
+					FullName:   fmt.Sprintf("%s/%s", org, repo.Name),
+					Visibility: "public",
+					GHAS:       true,
+					SourceFile: sourceFile,
+				})
 			}
 		}
 	}
-	return repoNames
+
+	sort.Slice(repos, func(i, j int) bool {
+		a, b := repos[i], repos[j]
+		if a.Org != b.Org {
+			return a.Org < b.Org
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	return repos
 }
@@ -0,0 +1,126 @@
+package list
+
+import (
+	"errors"
+	"fmt"
+	"gh_foundations/cmd/list/output"
+	"gh_foundations/internal/pkg/functions"
+	"gh_foundations/internal/pkg/types/status"
+	"gh_foundations/internal/pkg/types/terragrunt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var member string
+var orgs []string
+var keepGoing bool
+
+var TeamsCmd = &cobra.Command{
+	Use:   "teams",
+	Short: "List managed teams.",
+	Long: `List the teams of the "teams/terragrunt.hcl" team sets of every project, with their
+organization, project, privacy, maintainers, members and the team set they are defined in.
+
+Use --member to list the teams a user maintains or is a member of across all projects,
+and --org to only list the teams of some organizations.
+
+The command fails when a team set cannot be read. With --keep-going, the errors are
+printed to stderr and the teams of the other files are listed.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("requires the path of the \"projects\" directory")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		orgSet, err := functions.FindManagedTeams(args[0])
+		var configErrs terragrunt.ConfigErrors
+		if err != nil && keepGoing && errors.As(err, &configErrs) {
+			for _, configErr := range configErrs {
+				cmd.PrintErrf("Skipping %s\n", configErr)
+			}
+		} else if err != nil {
+			return fmt.Errorf("unable to read the managed teams: %w", err)
+		}
+
+		return output.Write(cmd.OutOrStdout(), filterTeams(orgSet, orgs, member), columns)
+	},
+}
+
+func init() {
+	TeamsCmd.Flags().StringVar(&member, "member", "", "Only list the teams the user maintains or is a member of")
+	TeamsCmd.Flags().StringSliceVar(&orgs, "org", nil, "Only list the teams of the organizations")
+	TeamsCmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Report the team sets that cannot be read and list the teams of the others")
+}
+
+type team struct {
+	Org         string   `json:"org" yaml:"org"`
+	Project     string   `json:"project" yaml:"project"`
+	Name        string   `json:"name" yaml:"name"`
+	Privacy     string   `json:"privacy" yaml:"privacy"`
+	Maintainers []string `json:"maintainers" yaml:"maintainers"`
+	Members     []string `json:"members" yaml:"members"`
+	// The team set the team is defined in
+	SourceFile string `json:"source_file" yaml:"source_file"`
+}
+
+var columns = []output.Column[team]{
+	{Header: "org", Value: func(t team) string { return t.Org }},
+	{Header: "project", Value: func(t team) string { return t.Project }},
+	{Header: "name", Value: func(t team) string { return t.Name }},
+	{Header: "privacy", Value: func(t team) string { return t.Privacy }},
+	{Header: "maintainers", Value: func(t team) string { return strings.Join(t.Maintainers, ",") }},
+	{Header: "members", Value: func(t team) string { return strings.Join(t.Members, ",") }},
+	{Header: "source_file", Value: func(t team) string { return t.SourceFile }},
+}
+
+// The teams of the organizations, or of every organization when none is given, that
+// have the member, sorted by organization, project and name
+func filterTeams(orgSet status.OrgSet, orgs []string, member string) []team {
+	var teams []team
+	for org, projects := range orgSet.OrgProjectSets {
+		if len(orgs) > 0 && !containsFold(orgs, org) {
+			continue
+		}
+		for project, teamSet := range projects.TeamSets {
+			for _, t := range teamSet.Teams {
+				if member != "" && !t.HasMember(member) {
+					continue
+				}
+				teams = append(teams, team{
+					Org:         org,
+					Project:     project,
+					Name:        t.Name,
+					Privacy:     t.Privacy,
+					Maintainers: append([]string{}, t.Maintainers...),
+					Members:     append([]string{}, t.Members...),
+					SourceFile:  projects.TeamSetPaths[project],
+				})
+			}
+		}
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		a, b := teams[i], teams[j]
+		if a.Org != b.Org {
+			return a.Org < b.Org
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return a.Name < b.Name
+	})
+	return teams
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
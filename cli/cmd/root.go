@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"gh_foundations/cmd/check"
+	"gh_foundations/cmd/drift"
+	"gh_foundations/cmd/exitcode"
 	"gh_foundations/cmd/gen"
+	"gh_foundations/cmd/githubclient"
 	import_cmd "gh_foundations/cmd/import"
 	"gh_foundations/cmd/list"
 	"os"
@@ -10,6 +13,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var cfgFile string
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "gh_foundations",
@@ -26,7 +31,7 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(exitcode.For(err))
 	}
 }
 
@@ -35,7 +40,9 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.tg_import.yaml)")
+	cobra.OnInitialize(initConfig)
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.gh_foundations.yaml)")
+	githubclient.AddFlags(rootCmd.PersistentFlags())
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -45,4 +52,9 @@ func init() {
 	rootCmd.AddCommand(gen.GenCmd)
 	rootCmd.AddCommand(check.CheckCmd)
 	rootCmd.AddCommand(list.ListCmd)
+	rootCmd.AddCommand(drift.DriftCmd)
+}
+
+func initConfig() {
+	cobra.CheckErr(githubclient.LoadConfig(cfgFile))
 }
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -25,16 +26,18 @@ func findOrgsFromFilenames(hclFiles []string) map[string][]string {
 
 
 // List all of the organizations managed by the tool's slugs
+// The providers.hcl files that cannot be read are skipped and reported in the returned
+// terragrunt.ConfigErrors, along with the slugs of the other files
 func FindManagedOrgSlugs(orgsDir string) ([]string, error) {
 
 	orgFiles, err := findConfigFiles(orgsDir, "providers.hcl")
 	if err != nil {
-		log.Fatalf("Error in findOrgFiles: %s", err)
-        return make([]string, 0), err
+		return make([]string, 0), err
 	}
 
 	// Walk the orgFiles and get all the providers.hcl files
 	var orgs []string
+	var errs terragrunt.ConfigErrors
 	for _, file := range orgFiles {
 		log.Printf("Working on file: %s\n", file)
 
@@ -42,7 +45,11 @@ func FindManagedOrgSlugs(orgsDir string) ([]string, error) {
 			Path: file,
 		}
 
-		locals := hclFile.GetLocalsMap()
+		locals, err := hclFile.GetLocalsMap()
+		if err != nil {
+			errs = errs.Add(file, err)
+			continue
+		}
 
 		// If the locals map has an `organization_name` key, then it is an org slug
 		if locals["organization_name"] != "" {
@@ -50,7 +57,7 @@ func FindManagedOrgSlugs(orgsDir string) ([]string, error) {
 		}
 	}
 
-	return orgs, nil
+	return orgs, errs.OrNil()
 }
 
 // List all of the relevant configs managed by the tool
@@ -90,23 +97,23 @@ func findConfigFiles(rootDir string, fileNamePattern ...string) ([]string, error
 
 
 // List all of the repositories managed by the tool
+// The repository sets that cannot be read are reported in the returned
+// terragrunt.ConfigErrors, along with the repositories of the other files
 func FindManagedRepos(reposDir string) (status.OrgSet, error) {
 	files, err := findConfigFiles(reposDir)
-
-	orgFiles := findOrgsFromFilenames(files)
-
 	if err != nil {
-		log.Fatalf("Error in findOrgFiles: %s", err)
-        return status.OrgSet{}, err
+		return status.OrgSet{}, err
 	}
 
+	orgFiles := findOrgsFromFilenames(files)
+
 	// Get the absolute path of the root directory
 	absRootPath, err := filepath.Abs(reposDir)
 	if err != nil {
-		log.Fatalf("Error in filepath.Abs: %s", err)
-        return status.OrgSet{}, err
+		return status.OrgSet{}, err
 	}
 
+	var errs terragrunt.ConfigErrors
 	var orgSet status.OrgSet
 	orgSet.OrgProjectSets = make(map[string]status.OrgProjectSet)
 
@@ -114,6 +121,7 @@ func FindManagedRepos(reposDir string) (status.OrgSet, error) {
 
 		var repos status.OrgProjectSet
 		repos.RepositorySets = make(map[string]githubfoundations.RepositorySetInput)
+		repos.RepositorySetPaths = make(map[string]string)
 		orgSet.OrgProjectSets[org] = repos
 
 		for _, file := range files {
@@ -137,18 +145,13 @@ func FindManagedRepos(reposDir string) (status.OrgSet, error) {
 					Path: file,
 				}
 
+				// The inputs that could be read are kept
 				inputs, err := hclFile.GetInputsFromFile()
-				if err != nil {
-					log.Fatalf(`Error in getInputsFromFile: %s`, err)
-					return orgSet, err
-				}
+				errs = errs.Add(file, err)
 
 				log.Printf("Repository Set has %d private repositories and %d public repositories", len(inputs.PrivateRepositories), len(inputs.PublicRepositories))
 				var repoSet githubfoundations.RepositorySetInput
-				for key, value := range inputs.DefaultRepositoryTeamPermissions {
-					repoSet.DefaultRepositoryTeamPermissions = make(map[string]string)
-					repoSet.DefaultRepositoryTeamPermissions[key] = value
-				}
+				repoSet.DefaultRepositoryTeamPermissions = inputs.DefaultRepositoryTeamPermissions
 
 				for name, repo := range inputs.PrivateRepositories {
 					// Coerce the repo into a githubfoundations.RepositoryInput
@@ -165,8 +168,60 @@ func FindManagedRepos(reposDir string) (status.OrgSet, error) {
 
 				// Add the repoSet to the orgSet
 				orgSet.OrgProjectSets[org].RepositorySets[project] = repoSet
+				orgSet.OrgProjectSets[org].RepositorySetPaths[project] = file
 			}
 		}
 	}
-	return orgSet, nil
+	return orgSet, errs.OrNil()
+}
+
+// List all of the teams managed by the tool, in the TeamSets of every organization
+// The team sets that cannot be read are reported in the returned terragrunt.ConfigErrors,
+// along with the teams of the other files
+func FindManagedTeams(projectsDir string) (status.OrgSet, error) {
+	files, err := findConfigFiles(projectsDir, "teams/terragrunt.hcl")
+	if err != nil {
+		return status.OrgSet{}, err
+	}
+
+	var errs terragrunt.ConfigErrors
+	orgSet := status.OrgSet{OrgProjectSets: make(map[string]status.OrgProjectSet)}
+	for org, files := range findOrgsFromFilenames(files) {
+		teams := status.OrgProjectSet{
+			TeamSets:     make(map[string]githubfoundations.TeamSetInput),
+			TeamSetPaths: make(map[string]string),
+		}
+		orgSet.OrgProjectSets[org] = teams
+
+		for _, file := range files {
+			file, err := filepath.Abs(file)
+			if err != nil {
+				return orgSet, err
+			}
+			log.Printf("Working on file: %s\n", file)
+
+			// Get the project name
+			parts := strings.Split(file, "/")
+			project := parts[len(parts)-4]
+
+			hclFile := terragrunt.HCLFile{
+				Path: file,
+			}
+
+			// The teams that could be read are kept
+			inputs, err := hclFile.GetTeamInputsFromFile()
+			errs = errs.Add(file, err)
+
+			var teamSet githubfoundations.TeamSetInput
+			for _, team := range inputs.Teams {
+				teamInput := team.GetTeamInput()
+				teamSet.Teams = append(teamSet.Teams, &teamInput)
+			}
+			sort.Slice(teamSet.Teams, func(i, j int) bool { return teamSet.Teams[i].Name < teamSet.Teams[j].Name })
+
+			teams.TeamSets[project] = teamSet
+			teams.TeamSetPaths[project] = file
+		}
+	}
+	return orgSet, errs.OrNil()
 }
@@ -0,0 +1,74 @@
+package functions
+
+import (
+	"gh_foundations/internal/pkg/types/terragrunt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestFindManagedReposReportsBrokenFiles(t *testing.T) {
+	projectsDir := t.TempDir()
+	valid := filepath.Join(projectsDir, "valid", "octo-org", "repositories", "terragrunt.hcl")
+	broken := filepath.Join(projectsDir, "broken", "octo-org", "repositories", "terragrunt.hcl")
+	unknown := filepath.Join(projectsDir, "unknown", "octo-org", "repositories", "terragrunt.hcl")
+	writeFile(t, valid, "inputs = {\n  public_repositories = {\n    app = {}\n  }\n}\n")
+	writeFile(t, broken, "inputs = {\n  public_repositories = {\n")
+	writeFile(t, unknown, "inputs = {\n  teams = {}\n}\n")
+
+	orgSet, err := FindManagedRepos(projectsDir)
+
+	var errs terragrunt.ConfigErrors
+	require.ErrorAs(t, err, &errs)
+	paths := make([]string, 0, len(errs))
+	for _, configErr := range errs {
+		paths = append(paths, configErr.Path)
+		assert.Greater(t, configErr.Line, 0)
+	}
+	assert.ElementsMatch(t, []string{broken, unknown}, paths)
+
+	// The repositories of the valid file are still listed
+	repoSet := orgSet.OrgProjectSets["octo-org"].RepositorySets["valid"]
+	require.Len(t, repoSet.PublicRepositories, 1)
+	assert.Equal(t, "app", repoSet.PublicRepositories[0].Name)
+}
+
+func TestFindManagedOrgSlugsReportsBrokenFiles(t *testing.T) {
+	providersDir := t.TempDir()
+	writeFile(t, filepath.Join(providersDir, "octo-org", "providers.hcl"), "locals {\n  organization_name = \"octo-org\"\n}\n")
+	broken := filepath.Join(providersDir, "broken-org", "providers.hcl")
+	writeFile(t, broken, "locals {\n  organization_name = local.missing\n}\n")
+
+	orgs, err := FindManagedOrgSlugs(providersDir)
+
+	assert.EqualError(t, err, broken+":2: local.organization_name refers to a local that is not defined or refers back to it")
+	assert.Equal(t, []string{"octo-org"}, orgs)
+}
+
+func TestFindManagedTeams(t *testing.T) {
+	projectsDir := t.TempDir()
+	teamSet := filepath.Join(projectsDir, "project", "octo-org", "teams", "terragrunt.hcl")
+	writeFile(t, teamSet, "inputs = {\n  teams = {\n    devs = {\n      privacy = \"closed\"\n      members = [\"octocat\"]\n    }\n    admins = {\n      privacy = \"secret\"\n    }\n  }\n}\n")
+	// Repository sets are not team sets
+	writeFile(t, filepath.Join(projectsDir, "project", "octo-org", "repositories", "terragrunt.hcl"), "inputs = {\n  private_repositories = {}\n}\n")
+
+	orgSet, err := FindManagedTeams(projectsDir)
+	require.NoError(t, err)
+
+	require.Contains(t, orgSet.OrgProjectSets, "octo-org")
+	projects := orgSet.OrgProjectSets["octo-org"]
+	assert.Equal(t, teamSet, projects.TeamSetPaths["project"])
+	teams := projects.TeamSets["project"].Teams
+	require.Len(t, teams, 2)
+	assert.Equal(t, "admins", teams[0].Name)
+	assert.Equal(t, "devs", teams[1].Name)
+	assert.Equal(t, []string{"octocat"}, teams[1].Members)
+}
@@ -2,6 +2,12 @@ package types
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
 )
 
 type CheckResult uint16
@@ -32,26 +38,238 @@ func (c CheckResult) MarshalJSON() ([]byte, error) {
 	return json.Marshal(c.String())
 }
 
+// UnmarshalJSON reads the results written by MarshalJSON, so saved reports can be loaded
+func (c *CheckResult) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for _, result := range []CheckResult{Failed, Passed, Errored, NotApplicable} {
+		if result.String() == name {
+			*c = result
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown check result %q", name)
+}
+
 type CheckError struct {
 	Err        error             `json:"-"`
 	Check      CheckType         `json:"check"`
 	Violations map[string]string `json:"violations"`
+	// The reasons the errored checks could not be evaluated, by check id
+	Errored map[string]string `json:"errored,omitempty"`
 }
 
 type CheckType string
 
 const (
-	GoCGuardrails = "GoCGuardrails"
+	GoCGuardrails   = "GoCGuardrails"
+	ActionsSecurity = "ActionsSecurity"
 )
 
+// ErrNotApplicable is returned by checks that do not apply to an entity, e.g. a check of
+// public repositories run against a private one
+var ErrNotApplicable = errors.New("check not applicable")
+
+// ErrEvaluation is returned by checks that cannot be evaluated, e.g. because the data they
+// read could not be fetched. The check is reported as errored rather than failed.
+var ErrEvaluation = errors.New("check could not be evaluated")
+
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// Rank orders severities from the least to the most severe
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	case SeverityCritical:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// ParseSeverity reads a severity name, rejecting unknown severities
+func ParseSeverity(value string) (Severity, error) {
+	severity := Severity(strings.ToLower(value))
+	if severity.Rank() == 0 {
+		return "", fmt.Errorf("unknown severity %q, expected one of low|medium|high|critical", value)
+	}
+	return severity, nil
+}
+
 type CheckReport struct {
-	EntityType 	string						`json:"entity_type"`
-	EntityId   	string						`json:"entity_id"`
-	Timestamp	string						`json:"rfc3339_timestamp"`
-	Results    	map[CheckType]CheckResult	`json:"results"`
-	Errors     	[]CheckError				`json:"errors"`
+	EntityType string                    `json:"entity_type"`
+	EntityId   string                    `json:"entity_id"`
+	Timestamp  string                    `json:"rfc3339_timestamp"`
+	Results    map[CheckType]CheckResult `json:"results"`
+	Checks     map[string]CheckResult    `json:"checks"`
+	Errors     []CheckError              `json:"errors"`
 }
 
 type ICheckable interface {
-	Check(checkTypes []CheckType) CheckReport
+	Check(selection CheckSelection) CheckReport
+}
+
+// CheckDefinition documents a single check
+type CheckDefinition struct {
+	Id         string
+	Type       CheckType
+	Title      string
+	EntityType string
+	Severity   Severity
+	Guardrails []Guardrail
+	// The ITSG-33 security controls the check assesses, e.g. AC-2(7)
+	Controls    []string
+	Remediation string
+}
+
+// Check is a named check that can be run against entities of type T
+type Check[T any] struct {
+	CheckDefinition
+	// Returns an error describing the violation when the entity does not pass the check
+	Evaluate func(entity T) error
+}
+
+// CheckSelection chooses which checks are run. A check is selected when it matches
+// one of the Include patterns (or Include is empty) and none of the Exclude patterns.
+// Patterns are matched against the check id (shell globs are allowed), the check
+// type and the ids of the guardrails the check maps to.
+type CheckSelection struct {
+	Include []string
+	Exclude []string
+}
+
+func (s CheckSelection) Selects(def CheckDefinition) bool {
+	if len(s.Include) > 0 && !slices.ContainsFunc(s.Include, def.matches) {
+		return false
+	}
+	return !slices.ContainsFunc(s.Exclude, def.matches)
+}
+
+func (d CheckDefinition) matches(pattern string) bool {
+	if matched, err := path.Match(pattern, d.Id); err == nil && matched {
+		return true
+	}
+	return pattern == string(d.Type) || slices.Contains(d.Guardrails, Guardrail(pattern))
+}
+
+// CheckRegistry holds the checks that apply to one entity type
+type CheckRegistry[T any] struct {
+	entityType string
+	checks     []Check[T]
+}
+
+func NewCheckRegistry[T any](entityType string) *CheckRegistry[T] {
+	return &CheckRegistry[T]{entityType: entityType}
+}
+
+// Register adds checks to the registry. Check ids must be unique within a registry.
+func (r *CheckRegistry[T]) Register(checks ...Check[T]) {
+	for _, check := range checks {
+		if check.Id == "" || check.Evaluate == nil {
+			panic("checks require an id and an Evaluate function")
+		}
+		if _, exists := r.Get(check.Id); exists {
+			panic(fmt.Sprintf("check %q is already registered for %s", check.Id, r.entityType))
+		}
+		if check.Type == "" {
+			check.Type = GoCGuardrails
+		}
+		check.EntityType = r.entityType
+		r.checks = append(r.checks, check)
+	}
+}
+
+func (r *CheckRegistry[T]) Get(id string) (Check[T], bool) {
+	for _, check := range r.checks {
+		if check.Id == id {
+			return check, true
+		}
+	}
+	return Check[T]{}, false
+}
+
+func (r *CheckRegistry[T]) Definitions() []CheckDefinition {
+	definitions := make([]CheckDefinition, len(r.checks))
+	for i, check := range r.checks {
+		definitions[i] = check.CheckDefinition
+	}
+	return definitions
+}
+
+// Run the selected checks against an entity and report the outcome per check type and per check
+func (r *CheckRegistry[T]) Run(entityId string, entity T, selection CheckSelection) CheckReport {
+	report := CheckReport{
+		EntityType: r.entityType,
+		EntityId:   entityId,
+		Timestamp:  time.Now().Format(time.RFC3339), // Syslog compliant timestamp
+		Results:    make(map[CheckType]CheckResult),
+		Checks:     make(map[string]CheckResult),
+		Errors:     []CheckError{},
+	}
+
+	checkErrors := make(map[CheckType]*CheckError)
+	var checkTypes []CheckType
+	for _, check := range r.checks {
+		if !selection.Selects(check.CheckDefinition) {
+			continue
+		}
+		if _, seen := report.Results[check.Type]; !seen {
+			report.Results[check.Type] = Passed
+			checkTypes = append(checkTypes, check.Type)
+		}
+
+		err := check.Evaluate(entity)
+		if err == nil {
+			report.Checks[check.Id] = Passed
+			continue
+		}
+		if errors.Is(err, ErrNotApplicable) {
+			report.Checks[check.Id] = NotApplicable
+			continue
+		}
+
+		checkErr, ok := checkErrors[check.Type]
+		if !ok {
+			checkErr = &CheckError{Check: check.Type, Violations: make(map[string]string)}
+			checkErrors[check.Type] = checkErr
+		}
+		checkErr.Err = errors.Join(checkErr.Err, err)
+
+		// A violation fails the check type, an errored check only when nothing failed
+		if errors.Is(err, ErrEvaluation) {
+			report.Checks[check.Id] = Errored
+			if report.Results[check.Type] != Failed {
+				report.Results[check.Type] = Errored
+			}
+			if checkErr.Errored == nil {
+				checkErr.Errored = make(map[string]string)
+			}
+			checkErr.Errored[check.Id] = err.Error()
+			continue
+		}
+		report.Checks[check.Id] = Failed
+		report.Results[check.Type] = Failed
+		checkErr.Violations[check.Id] = err.Error()
+	}
+
+	for _, t := range checkTypes {
+		if checkErr, ok := checkErrors[t]; ok {
+			report.Errors = append(report.Errors, *checkErr)
+		}
+	}
+	return report
 }
@@ -0,0 +1,161 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEntity struct {
+	name    string
+	enabled bool
+}
+
+func newTestRegistry() *CheckRegistry[*testEntity] {
+	registry := NewCheckRegistry[*testEntity]("test_entity")
+	registry.Register(
+		Check[*testEntity]{
+			CheckDefinition: CheckDefinition{Id: "enabled", Severity: SeverityHigh, Guardrails: []Guardrail{ManageAccess}},
+			Evaluate: func(e *testEntity) error {
+				if !e.enabled {
+					return errors.New("not enabled")
+				}
+				return nil
+			},
+		},
+		Check[*testEntity]{
+			CheckDefinition: CheckDefinition{Id: "named", Type: "Department", Severity: SeverityLow, Guardrails: []Guardrail{DataProtection}},
+			Evaluate: func(e *testEntity) error {
+				if e.name == "" {
+					return errors.New("no name")
+				}
+				return nil
+			},
+		},
+	)
+	return registry
+}
+
+func TestCheckRegistryRun(t *testing.T) {
+	registry := newTestRegistry()
+
+	report := registry.Run("entity", &testEntity{name: "entity"}, CheckSelection{})
+
+	assert.Equal(t, "test_entity", report.EntityType)
+	assert.Equal(t, "entity", report.EntityId)
+	assert.Equal(t, map[CheckType]CheckResult{GoCGuardrails: Failed, "Department": Passed}, report.Results)
+	assert.Equal(t, map[string]CheckResult{"enabled": Failed, "named": Passed}, report.Checks)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, CheckType(GoCGuardrails), report.Errors[0].Check)
+	assert.Equal(t, map[string]string{"enabled": "not enabled"}, report.Errors[0].Violations)
+}
+
+func TestCheckRegistryRunNotApplicable(t *testing.T) {
+	registry := newTestRegistry()
+	registry.Register(Check[*testEntity]{
+		CheckDefinition: CheckDefinition{Id: "public", Type: ActionsSecurity},
+		Evaluate:        func(e *testEntity) error { return ErrNotApplicable },
+	})
+
+	report := registry.Run("entity", &testEntity{name: "entity", enabled: true}, CheckSelection{})
+
+	assert.Equal(t, CheckResult(NotApplicable), report.Checks["public"])
+	assert.Equal(t, CheckResult(Passed), report.Results[ActionsSecurity])
+	assert.Empty(t, report.Errors)
+}
+
+func TestCheckRegistryRunErrored(t *testing.T) {
+	registry := newTestRegistry()
+	registry.Register(Check[*testEntity]{
+		CheckDefinition: CheckDefinition{Id: "owners", Type: "Department"},
+		Evaluate: func(e *testEntity) error {
+			return fmt.Errorf("%w: owners could not be fetched", ErrEvaluation)
+		},
+	})
+
+	report := registry.Run("entity", &testEntity{name: "entity", enabled: true}, CheckSelection{})
+
+	assert.Equal(t, CheckResult(Errored), report.Checks["owners"])
+	assert.Equal(t, CheckResult(Errored), report.Results["Department"])
+	assert.Equal(t, CheckResult(Passed), report.Results[GoCGuardrails])
+	require.Len(t, report.Errors, 1)
+	assert.Empty(t, report.Errors[0].Violations)
+	assert.Equal(t, map[string]string{"owners": "check could not be evaluated: owners could not be fetched"}, report.Errors[0].Errored)
+
+	// A violation of the same check type takes precedence
+	report = registry.Run("entity", &testEntity{enabled: true}, CheckSelection{})
+	assert.Equal(t, CheckResult(Failed), report.Results["Department"])
+	assert.Equal(t, CheckResult(Errored), report.Checks["owners"])
+}
+
+func TestCheckRegistryRunSelection(t *testing.T) {
+	registry := newTestRegistry()
+	entity := &testEntity{}
+
+	tests := []struct {
+		selection CheckSelection
+		expected  []string
+	}{
+		{CheckSelection{}, []string{"enabled", "named"}},
+		{CheckSelection{Include: []string{"nam*"}}, []string{"named"}},
+		{CheckSelection{Exclude: []string{"enabled"}}, []string{"named"}},
+		{CheckSelection{Include: []string{"02"}}, []string{"enabled"}},
+		{CheckSelection{Include: []string{"Department"}}, []string{"named"}},
+		{CheckSelection{Include: []string{"*"}, Exclude: []string{"05", "02"}}, []string{}},
+	}
+
+	for _, test := range tests {
+		report := registry.Run("entity", entity, test.selection)
+		ids := make([]string, 0)
+		for id := range report.Checks {
+			ids = append(ids, id)
+		}
+		assert.ElementsMatch(t, test.expected, ids, "%+v", test.selection)
+	}
+}
+
+func TestCheckRegistryRegisterDuplicate(t *testing.T) {
+	registry := newTestRegistry()
+
+	assert.Panics(t, func() {
+		registry.Register(Check[*testEntity]{
+			CheckDefinition: CheckDefinition{Id: "enabled"},
+			Evaluate:        func(e *testEntity) error { return nil },
+		})
+	})
+}
+
+func TestCheckRegistryDefinitions(t *testing.T) {
+	definitions := newTestRegistry().Definitions()
+
+	require.Len(t, definitions, 2)
+	assert.Equal(t, "test_entity", definitions[0].EntityType)
+	assert.Equal(t, CheckType(GoCGuardrails), definitions[0].Type)
+	assert.Equal(t, CheckType("Department"), definitions[1].Type)
+}
+
+func TestParseSeverity(t *testing.T) {
+	severity, err := ParseSeverity("High")
+	require.NoError(t, err)
+	assert.Equal(t, SeverityHigh, severity)
+
+	_, err = ParseSeverity("urgent")
+	assert.EqualError(t, err, `unknown severity "urgent", expected one of low|medium|high|critical`)
+}
+
+func TestCheckResultJSON(t *testing.T) {
+	results := map[string]CheckResult{"a": Passed, "b": Failed, "c": NotApplicable}
+	data, err := json.Marshal(results)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": "Passed", "b": "Failed", "c": "Not Applicable"}`, string(data))
+
+	var decoded map[string]CheckResult
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, results, decoded)
+
+	assert.EqualError(t, json.Unmarshal([]byte(`"Skipped"`), new(CheckResult)), `unknown check result "Skipped"`)
+}
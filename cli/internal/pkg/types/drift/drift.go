@@ -0,0 +1,258 @@
+package drift
+
+import (
+	_ "embed"
+	"encoding/json"
+	"gh_foundations/internal/pkg/types/github"
+	githubfoundations "gh_foundations/internal/pkg/types/github_foundations"
+	"gh_foundations/internal/pkg/types/status"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	gh "github.com/google/go-github/v61/github"
+)
+
+type Status string
+
+const (
+	// The live settings of the repository differ from its inputs
+	Drifted Status = "drifted"
+	// The repository is managed but does not exist in the organization
+	Missing Status = "missing"
+	// The repository exists in the organization but is not managed
+	Unmanaged Status = "unmanaged"
+)
+
+// FieldDrift is an input of a repository whose live value differs from the configured one
+type FieldDrift struct {
+	// The RepositoryInput attribute, e.g. default_branch
+	Field      string `json:"field"`
+	Configured any    `json:"configured"`
+	Live       any    `json:"live"`
+}
+
+// RepositoryDrift is a repository whose live settings do not match the configuration
+type RepositoryDrift struct {
+	Organization string       `json:"organization"`
+	Project      string       `json:"project,omitempty"`
+	Repository   string       `json:"repository"`
+	Status       Status       `json:"status"`
+	Fields       []FieldDrift `json:"fields,omitempty"`
+}
+
+// Report lists the repositories that drifted from the Terragrunt configuration
+type Report struct {
+	// The number of managed repositories matching the configuration
+	InSync       int               `json:"in_sync"`
+	Repositories []RepositoryDrift `json:"repositories"`
+}
+
+// A compared input, with its configured value and its live value, unknown when the
+// token cannot read it
+type field struct {
+	name       string
+	configured func(repo *githubfoundations.RepositoryInput) any
+	live       func(repo *gh.Repository) (any, bool)
+}
+
+var fields = []field{
+	{
+		name:       "description",
+		configured: func(r *githubfoundations.RepositoryInput) any { return r.Description },
+		live:       func(r *gh.Repository) (any, bool) { return r.GetDescription(), true },
+	},
+	{
+		name:       "homepage",
+		configured: func(r *githubfoundations.RepositoryInput) any { return r.Homepage },
+		live:       func(r *gh.Repository) (any, bool) { return r.GetHomepage(), true },
+	},
+	{
+		name:       "default_branch",
+		configured: func(r *githubfoundations.RepositoryInput) any { return r.DefaultBranch },
+		live:       func(r *gh.Repository) (any, bool) { return r.GetDefaultBranch(), true },
+	},
+	{
+		name:       "topics",
+		configured: func(r *githubfoundations.RepositoryInput) any { return sortedTopics(r.Topics) },
+		live:       func(r *gh.Repository) (any, bool) { return sortedTopics(r.Topics), true },
+	},
+	{
+		name:       "delete_head_on_merge",
+		configured: func(r *githubfoundations.RepositoryInput) any { return r.DeleteHeadBranchOnMerge },
+		live:       func(r *gh.Repository) (any, bool) { return r.GetDeleteBranchOnMerge(), r.DeleteBranchOnMerge != nil },
+	},
+	{
+		name:       "allow_auto_merge",
+		configured: func(r *githubfoundations.RepositoryInput) any { return r.AllowAutoMerge },
+		live:       func(r *gh.Repository) (any, bool) { return r.GetAllowAutoMerge(), r.AllowAutoMerge != nil },
+	},
+	{
+		name:       "requires_web_commit_signing",
+		configured: func(r *githubfoundations.RepositoryInput) any { return r.RequiresWebCommitSignOff },
+		live: func(r *gh.Repository) (any, bool) {
+			return r.GetWebCommitSignoffRequired(), r.WebCommitSignoffRequired != nil
+		},
+	},
+	{
+		name:       "advance_security",
+		configured: func(r *githubfoundations.RepositoryInput) any { return r.AdvanceSecurity },
+		live: func(r *gh.Repository) (any, bool) {
+			sa := r.GetSecurityAndAnalysis()
+			if sa == nil || sa.AdvancedSecurity == nil {
+				return nil, false
+			}
+			return sa.AdvancedSecurity.GetStatus() == "enabled", true
+		},
+	},
+	{
+		name:       "dependabot_security_updates",
+		configured: func(r *githubfoundations.RepositoryInput) any { return r.DependabotSecurityUpdates },
+		live: func(r *gh.Repository) (any, bool) {
+			sa := r.GetSecurityAndAnalysis()
+			if sa == nil || sa.DependabotSecurityUpdates == nil {
+				return nil, false
+			}
+			return sa.DependabotSecurityUpdates.GetStatus() == "enabled", true
+		},
+	},
+}
+
+type managedRepository struct {
+	project    string
+	visibility string
+	input      *githubfoundations.RepositoryInput
+}
+
+// Compare compares the managed repositories of an organization with its live repositories.
+// Inputs the repository sets do not give and settings the token cannot read are not
+// compared.
+func Compare(org string, managed status.OrgSet, live []github.Repository) Report {
+	repos := make(map[string]managedRepository)
+	for orgName, projects := range managed.OrgProjectSets {
+		if !strings.EqualFold(orgName, org) {
+			continue
+		}
+		for project, repoSet := range projects.RepositorySets {
+			for _, repo := range repoSet.PrivateRepositories {
+				repos[strings.ToLower(repo.Name)] = managedRepository{project: project, visibility: "private", input: repo}
+			}
+			for _, repo := range repoSet.PublicRepositories {
+				repos[strings.ToLower(repo.Name)] = managedRepository{project: project, visibility: "public", input: repo}
+			}
+		}
+	}
+
+	report := Report{Repositories: []RepositoryDrift{}}
+	found := make(map[string]bool)
+	for _, r := range live {
+		key := strings.ToLower(r.GetName())
+		repo, ok := repos[key]
+		if !ok {
+			report.Repositories = append(report.Repositories, RepositoryDrift{Organization: org, Repository: r.GetName(), Status: Unmanaged})
+			continue
+		}
+		found[key] = true
+
+		drift := RepositoryDrift{Organization: org, Project: repo.project, Repository: repo.input.Name, Status: Drifted}
+		if r.GetVisibility() != repo.visibility {
+			drift.Fields = append(drift.Fields, FieldDrift{Field: "visibility", Configured: repo.visibility, Live: r.GetVisibility()})
+		}
+		for _, f := range fields {
+			// Inputs the configuration leaves out keep whatever value they have on GitHub
+			if !repo.input.IsSet(f.name) {
+				continue
+			}
+			liveValue, known := f.live(r.Repository)
+			if configured := f.configured(repo.input); known && !equal(configured, liveValue) {
+				drift.Fields = append(drift.Fields, FieldDrift{Field: f.name, Configured: configured, Live: liveValue})
+			}
+		}
+		if len(drift.Fields) == 0 {
+			report.InSync++
+			continue
+		}
+		report.Repositories = append(report.Repositories, drift)
+	}
+
+	for key, repo := range repos {
+		if !found[key] {
+			report.Repositories = append(report.Repositories, RepositoryDrift{Organization: org, Project: repo.project, Repository: repo.input.Name, Status: Missing})
+		}
+	}
+	report.sort()
+	return report
+}
+
+// Append adds the repositories of another report, e.g. of another organization
+func (r *Report) Append(other Report) {
+	r.InSync += other.InSync
+	r.Repositories = append(r.Repositories, other.Repositories...)
+	r.sort()
+}
+
+func (r *Report) sort() {
+	sort.SliceStable(r.Repositories, func(i, j int) bool {
+		a, b := r.Repositories[i], r.Repositories[j]
+		if a.Organization != b.Organization {
+			return a.Organization < b.Organization
+		}
+		return strings.ToLower(a.Repository) < strings.ToLower(b.Repository)
+	})
+}
+
+// Count counts the repositories with the status
+func (r Report) Count(s Status) int {
+	n := 0
+	for _, repo := range r.Repositories {
+		if repo.Status == s {
+			n++
+		}
+	}
+	return n
+}
+
+//go:embed drift.md.tmpl
+var markdown string
+
+var markdownTemplate = template.Must(template.New("drift.md.tmpl").Funcs(map[string]any{
+	"value": formatValue,
+	"count": func(r Report, s string) int { return r.Count(Status(s)) },
+}).Parse(markdown))
+
+// WriteJSON writes the report as indented JSON
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteMarkdown writes the report as a Markdown document, e.g. for an issue or a job summary
+func (r Report) WriteMarkdown(w io.Writer) error {
+	return markdownTemplate.Execute(w, r)
+}
+
+func equal(configured any, live any) bool {
+	if a, ok := configured.([]string); ok {
+		b, ok := live.([]string)
+		return ok && slices.Equal(a, b)
+	}
+	return configured == live
+}
+
+func sortedTopics(topics []string) []string {
+	sorted := append([]string{}, topics...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// Values are shown as they are written in HCL, escaped for Markdown table cells
+func formatValue(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(string(encoded))
+}
@@ -0,0 +1,127 @@
+package drift
+
+import (
+	"bytes"
+	"gh_foundations/internal/pkg/types/github"
+	githubfoundations "gh_foundations/internal/pkg/types/github_foundations"
+	"gh_foundations/internal/pkg/types/status"
+	"testing"
+
+	gh "github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOrgSet() status.OrgSet {
+	return status.OrgSet{OrgProjectSets: map[string]status.OrgProjectSet{
+		"octo-org": {RepositorySets: map[string]githubfoundations.RepositorySetInput{
+			"project": {
+				PrivateRepositories: []*githubfoundations.RepositoryInput{
+					{Name: "app", Description: "The app", DefaultBranch: "main", Topics: []string{"go", "api"}, DeleteHeadBranchOnMerge: true, AdvanceSecurity: true},
+					{Name: "deleted", DefaultBranch: "main"},
+				},
+				PublicRepositories: []*githubfoundations.RepositoryInput{
+					{Name: "docs", Description: "Docs", DefaultBranch: "main", DeleteHeadBranchOnMerge: true},
+				},
+			},
+		}},
+		"other-org": {RepositorySets: map[string]githubfoundations.RepositorySetInput{
+			"project": {PrivateRepositories: []*githubfoundations.RepositoryInput{{Name: "sandbox"}}},
+		}},
+	}}
+}
+
+func liveRepository(r *gh.Repository) github.Repository {
+	return github.Repository{Repository: r}
+}
+
+func TestCompare(t *testing.T) {
+	live := []github.Repository{
+		liveRepository(&gh.Repository{
+			Name:                gh.String("App"),
+			Description:         gh.String("The app"),
+			DefaultBranch:       gh.String("develop"),
+			Visibility:          gh.String("internal"),
+			Topics:              []string{"api", "go"},
+			DeleteBranchOnMerge: gh.Bool(false),
+			SecurityAndAnalysis: &gh.SecurityAndAnalysis{AdvancedSecurity: &gh.AdvancedSecurity{Status: gh.String("enabled")}},
+		}),
+		// The listing does not include delete_branch_on_merge without admin access
+		liveRepository(&gh.Repository{Name: gh.String("docs"), Description: gh.String("Docs"), DefaultBranch: gh.String("main"), Visibility: gh.String("public")}),
+		liveRepository(&gh.Repository{Name: gh.String("scratch"), Visibility: gh.String("private")}),
+	}
+
+	report := Compare("Octo-Org", testOrgSet(), live)
+
+	assert.Equal(t, 1, report.InSync)
+	assert.Equal(t, []RepositoryDrift{
+		{Organization: "Octo-Org", Project: "project", Repository: "app", Status: Drifted, Fields: []FieldDrift{
+			{Field: "visibility", Configured: "private", Live: "internal"},
+			{Field: "default_branch", Configured: "main", Live: "develop"},
+			{Field: "delete_head_on_merge", Configured: true, Live: false},
+		}},
+		{Organization: "Octo-Org", Project: "project", Repository: "deleted", Status: Missing},
+		{Organization: "Octo-Org", Repository: "scratch", Status: Unmanaged},
+	}, report.Repositories)
+	assert.Equal(t, 1, report.Count(Missing))
+}
+
+func TestCompareSkipsUnsetInputs(t *testing.T) {
+	managed := status.OrgSet{OrgProjectSets: map[string]status.OrgProjectSet{
+		"octo-org": {RepositorySets: map[string]githubfoundations.RepositorySetInput{
+			"project": {PrivateRepositories: []*githubfoundations.RepositoryInput{
+				{Name: "app", DefaultBranch: "main", AllowAutoMerge: true, SetInputs: []string{"allow_auto_merge", "default_branch"}},
+			}},
+		}},
+	}}
+	live := []github.Repository{liveRepository(&gh.Repository{
+		Name:                gh.String("app"),
+		Description:         gh.String("Set in the GitHub settings"),
+		Homepage:            gh.String("https://example.com"),
+		DefaultBranch:       gh.String("main"),
+		Visibility:          gh.String("private"),
+		Topics:              []string{"go"},
+		DeleteBranchOnMerge: gh.Bool(true),
+		AllowAutoMerge:      gh.Bool(false),
+	})}
+
+	report := Compare("octo-org", managed, live)
+
+	assert.Equal(t, []RepositoryDrift{
+		{Organization: "octo-org", Project: "project", Repository: "app", Status: Drifted, Fields: []FieldDrift{
+			{Field: "allow_auto_merge", Configured: true, Live: false},
+		}},
+	}, report.Repositories)
+}
+
+func TestReportAppend(t *testing.T) {
+	report := Compare("other-org", testOrgSet(), nil)
+	report.Append(Compare("octo-org", testOrgSet(), nil))
+
+	assert.Equal(t, 4, report.Count(Missing))
+	assert.Equal(t, "octo-org", report.Repositories[0].Organization)
+	assert.Equal(t, "sandbox", report.Repositories[3].Repository)
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	report := Report{InSync: 2, Repositories: []RepositoryDrift{
+		{Organization: "octo-org", Project: "project", Repository: "app", Status: Drifted, Fields: []FieldDrift{
+			{Field: "topics", Configured: []string{"api"}, Live: []string{}},
+			{Field: "description", Configured: "a | b", Live: ""},
+		}},
+		{Organization: "octo-org", Repository: "scratch", Status: Unmanaged},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteMarkdown(&buf))
+	assert.Equal(t, `# Configuration drift
+
+Managed repositories matching the configuration: 2. Drifted: 1, missing: 0, unmanaged: 1.
+
+| Organization | Project | Repository | Status | Field | Configured | Live |
+| --- | --- | --- | --- | --- | --- | --- |
+| octo-org | project | app | drifted | topics | ["api"] | [] |
+| octo-org | project | app | drifted | description | "a \| b" | "" |
+| octo-org |  | scratch | unmanaged | | | |
+`, buf.String())
+}
@@ -0,0 +1,33 @@
+package github
+
+import "github.com/google/go-github/v61/github"
+
+// ActionsSettings are the GitHub Actions permissions of an organization or a repository.
+// Settings the token is not allowed to read are left unset.
+type ActionsSettings struct {
+	// Repositories only, whether Actions can run in the repository
+	Enabled *bool `json:"enabled,omitempty"`
+	// Organizations only, the repositories Actions can run in: all, selected or none
+	EnabledRepositories *string `json:"enabled_repositories,omitempty"`
+	// The actions workflows can use: all, local_only or selected
+	AllowedActions *string `json:"allowed_actions,omitempty"`
+	// The actions allowed when allowed_actions is selected
+	SelectedActions *github.ActionsAllowed `json:"selected_actions,omitempty"`
+	// The permissions of the GITHUB_TOKEN of workflows that do not set any: read or write
+	DefaultWorkflowPermissions   *string `json:"default_workflow_permissions,omitempty"`
+	CanApprovePullRequestReviews *bool   `json:"can_approve_pull_request_reviews,omitempty"`
+	// Organizations and public repositories only, the contributors whose fork pull
+	// requests need an approval before workflows run:
+	// first_time_contributors_new_to_github, first_time_contributors or all_external_contributors
+	ForkPrApprovalPolicy *string `json:"fork_pr_approval_policy,omitempty"`
+	// Organizations only, the names of the self-hosted runner groups public repositories can use
+	PublicRunnerGroups []string `json:"public_runner_groups"`
+	// Public repositories only, the names of the self-hosted runners registered to the repository
+	SelfHostedRunners []string `json:"self_hosted_runners"`
+}
+
+// forkPrApproval is the response of the fork pull request contributor approval
+// endpoints, which go-github does not support yet
+type forkPrApproval struct {
+	ApprovalPolicy *string `json:"approval_policy"`
+}
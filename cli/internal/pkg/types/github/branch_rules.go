@@ -0,0 +1,214 @@
+package github
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/google/go-github/v61/github"
+)
+
+// Where branch requirements come from besides rulesets
+const branchProtectionSource = "BranchProtection"
+
+// BranchRules are the effective requirements of a branch. Every ruleset that targets the
+// branch, whether defined by the repository or the organization, and its classic branch
+// protection apply together, so the strictest value of each requirement is kept.
+type BranchRules struct {
+	// Only users with bypass permissions can create, update or delete the branch
+	Creation bool `json:"creation"`
+	Update   bool `json:"update"`
+	Deletion bool `json:"deletion"`
+	// Force pushes are blocked
+	NonFastForward        bool `json:"non_fast_forward"`
+	RequiredLinearHistory bool `json:"required_linear_history"`
+	RequiredSignatures    bool `json:"required_signatures"`
+	// Set when changes must be made through a pull request
+	PullRequest          *PullRequestRule      `json:"pull_request,omitempty"`
+	RequiredStatusChecks *RequiredStatusChecks `json:"required_status_checks,omitempty"`
+	RequiredDeployments  []string              `json:"required_deployments"`
+	// The paths of the workflows that must pass
+	Workflows []string      `json:"workflows"`
+	Patterns  []PatternRule `json:"patterns"`
+	// The types of the ruleset rules that apply, including the types not parsed here such
+	// as merge_queue or code_scanning
+	RuleTypes []string `json:"rule_types"`
+	// The origins of the requirements: Repository, Organization or BranchProtection
+	Sources []string `json:"sources"`
+}
+
+type PullRequestRule struct {
+	RequiredApprovingReviewCount   int  `json:"required_approving_review_count"`
+	DismissStaleReviewsOnPush      bool `json:"dismiss_stale_reviews_on_push"`
+	RequireCodeOwnerReview         bool `json:"require_code_owner_review"`
+	RequireLastPushApproval        bool `json:"require_last_push_approval"`
+	RequiredReviewThreadResolution bool `json:"required_review_thread_resolution"`
+}
+
+type RequiredStatusChecks struct {
+	Contexts []string `json:"contexts"`
+	// Branches must be up to date before merging
+	Strict bool `json:"strict"`
+}
+
+// PatternRule restricts commit messages, author or committer emails, or branch and tag names
+type PatternRule struct {
+	Type     string `json:"type"`
+	Operator string `json:"operator"`
+	Pattern  string `json:"pattern"`
+	Negate   bool   `json:"negate"`
+}
+
+// branchRule is a rule as returned by the rules for a branch endpoint. go-github
+// refuses to decode rule types it does not know, so the parameters are kept raw.
+type branchRule struct {
+	Type              string          `json:"type"`
+	RulesetSourceType string          `json:"ruleset_source_type"`
+	RulesetSource     string          `json:"ruleset_source"`
+	RulesetID         int64           `json:"ruleset_id"`
+	Parameters        json.RawMessage `json:"parameters,omitempty"`
+}
+
+// newBranchRules merges the rules that apply to a branch with its classic branch
+// protection, which may be nil when the branch is not protected.
+func newBranchRules(rules []branchRule, protection *github.Protection) BranchRules {
+	b := BranchRules{
+		RequiredDeployments: []string{},
+		Workflows:           []string{},
+		Patterns:            []PatternRule{},
+		RuleTypes:           []string{},
+		Sources:             []string{},
+	}
+	for _, rule := range rules {
+		b.addRule(rule)
+	}
+	if protection != nil {
+		b.addProtection(protection)
+	}
+	return b
+}
+
+func (b *BranchRules) addRule(rule branchRule) {
+	b.RuleTypes = appendUnique(b.RuleTypes, rule.Type)
+	if rule.RulesetSourceType != "" {
+		b.Sources = appendUnique(b.Sources, rule.RulesetSourceType)
+	}
+
+	switch rule.Type {
+	case "creation":
+		b.Creation = true
+	case "update":
+		b.Update = true
+	case "deletion":
+		b.Deletion = true
+	case "non_fast_forward":
+		b.NonFastForward = true
+	case "required_linear_history":
+		b.RequiredLinearHistory = true
+	case "required_signatures":
+		b.RequiredSignatures = true
+	case "pull_request":
+		var params github.PullRequestRuleParameters
+		if json.Unmarshal(rule.Parameters, &params) == nil {
+			b.requirePullRequest(PullRequestRule{
+				RequiredApprovingReviewCount:   params.RequiredApprovingReviewCount,
+				DismissStaleReviewsOnPush:      params.DismissStaleReviewsOnPush,
+				RequireCodeOwnerReview:         params.RequireCodeOwnerReview,
+				RequireLastPushApproval:        params.RequireLastPushApproval,
+				RequiredReviewThreadResolution: params.RequiredReviewThreadResolution,
+			})
+		}
+	case "required_status_checks":
+		var params github.RequiredStatusChecksRuleParameters
+		if json.Unmarshal(rule.Parameters, &params) == nil {
+			contexts := make([]string, 0, len(params.RequiredStatusChecks))
+			for _, check := range params.RequiredStatusChecks {
+				contexts = append(contexts, check.Context)
+			}
+			b.requireStatusChecks(contexts, params.StrictRequiredStatusChecksPolicy)
+		}
+	case "required_deployments":
+		var params github.RequiredDeploymentEnvironmentsRuleParameters
+		if json.Unmarshal(rule.Parameters, &params) == nil {
+			b.RequiredDeployments = appendUnique(b.RequiredDeployments, params.RequiredDeploymentEnvironments...)
+		}
+	case "workflows":
+		var params github.RequiredWorkflowsRuleParameters
+		if json.Unmarshal(rule.Parameters, &params) == nil {
+			for _, workflow := range params.RequiredWorkflows {
+				b.Workflows = appendUnique(b.Workflows, workflow.Path)
+			}
+		}
+	case "commit_message_pattern", "commit_author_email_pattern", "committer_email_pattern", "branch_name_pattern", "tag_name_pattern":
+		var params github.RulePatternParameters
+		if json.Unmarshal(rule.Parameters, &params) == nil {
+			pattern := PatternRule{Type: rule.Type, Operator: params.Operator, Pattern: params.Pattern, Negate: params.GetNegate()}
+			if !slices.Contains(b.Patterns, pattern) {
+				b.Patterns = append(b.Patterns, pattern)
+			}
+		}
+	}
+}
+
+// Classic branch protection settings map to the equivalent ruleset rules
+func (b *BranchRules) addProtection(p *github.Protection) {
+	b.Sources = appendUnique(b.Sources, branchProtectionSource)
+
+	if reviews := p.GetRequiredPullRequestReviews(); reviews != nil {
+		b.requirePullRequest(PullRequestRule{
+			RequiredApprovingReviewCount:   reviews.RequiredApprovingReviewCount,
+			DismissStaleReviewsOnPush:      reviews.DismissStaleReviews,
+			RequireCodeOwnerReview:         reviews.RequireCodeOwnerReviews,
+			RequireLastPushApproval:        reviews.RequireLastPushApproval,
+			RequiredReviewThreadResolution: p.RequiredConversationResolution != nil && p.RequiredConversationResolution.Enabled,
+		})
+	}
+	if checks := p.GetRequiredStatusChecks(); checks != nil {
+		var contexts []string
+		if checks.Checks != nil {
+			for _, check := range *checks.Checks {
+				contexts = append(contexts, check.Context)
+			}
+		} else if checks.Contexts != nil {
+			contexts = *checks.Contexts
+		}
+		b.requireStatusChecks(contexts, checks.Strict)
+	}
+
+	b.Creation = b.Creation || p.GetBlockCreations().GetEnabled()
+	b.Update = b.Update || p.GetLockBranch().GetEnabled()
+	// Protected branches cannot be deleted or force pushed unless explicitly allowed
+	b.Deletion = b.Deletion || p.AllowDeletions == nil || !p.AllowDeletions.Enabled
+	b.NonFastForward = b.NonFastForward || p.AllowForcePushes == nil || !p.AllowForcePushes.Enabled
+	b.RequiredLinearHistory = b.RequiredLinearHistory || (p.RequireLinearHistory != nil && p.RequireLinearHistory.Enabled)
+	b.RequiredSignatures = b.RequiredSignatures || p.GetRequiredSignatures().GetEnabled()
+}
+
+func (b *BranchRules) requirePullRequest(rule PullRequestRule) {
+	if b.PullRequest == nil {
+		b.PullRequest = &rule
+		return
+	}
+	b.PullRequest.RequiredApprovingReviewCount = max(b.PullRequest.RequiredApprovingReviewCount, rule.RequiredApprovingReviewCount)
+	b.PullRequest.DismissStaleReviewsOnPush = b.PullRequest.DismissStaleReviewsOnPush || rule.DismissStaleReviewsOnPush
+	b.PullRequest.RequireCodeOwnerReview = b.PullRequest.RequireCodeOwnerReview || rule.RequireCodeOwnerReview
+	b.PullRequest.RequireLastPushApproval = b.PullRequest.RequireLastPushApproval || rule.RequireLastPushApproval
+	b.PullRequest.RequiredReviewThreadResolution = b.PullRequest.RequiredReviewThreadResolution || rule.RequiredReviewThreadResolution
+}
+
+func (b *BranchRules) requireStatusChecks(contexts []string, strict bool) {
+	if b.RequiredStatusChecks == nil {
+		b.RequiredStatusChecks = &RequiredStatusChecks{Contexts: []string{}}
+	}
+	b.RequiredStatusChecks.Contexts = appendUnique(b.RequiredStatusChecks.Contexts, contexts...)
+	b.RequiredStatusChecks.Strict = b.RequiredStatusChecks.Strict || strict
+}
+
+// Append the values missing from a sorted list, keeping it sorted
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		if i, found := slices.BinarySearch(list, value); !found {
+			list = slices.Insert(list, i, value)
+		}
+	}
+	return list
+}
@@ -0,0 +1,78 @@
+package github
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBranchRulesParsesRuleTypes(t *testing.T) {
+	var rules []branchRule
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"type": "deletion", "ruleset_source_type": "Repository"},
+		{"type": "non_fast_forward", "ruleset_source_type": "Repository"},
+		{"type": "required_signatures", "ruleset_source_type": "Organization"},
+		{"type": "required_status_checks", "ruleset_source_type": "Organization",
+		 "parameters": {"required_status_checks": [{"context": "build"}, {"context": "test"}], "strict_required_status_checks_policy": true}},
+		{"type": "required_status_checks", "ruleset_source_type": "Repository",
+		 "parameters": {"required_status_checks": [{"context": "lint"}, {"context": "build"}]}},
+		{"type": "required_deployments", "parameters": {"required_deployment_environments": ["staging"]}},
+		{"type": "workflows", "parameters": {"workflows": [{"path": ".github/workflows/scan.yml", "repository_id": 1}]}},
+		{"type": "commit_message_pattern", "parameters": {"operator": "starts_with", "pattern": "feat", "negate": false}},
+		{"type": "code_scanning", "parameters": {"code_scanning_tools": []}}
+	]`), &rules))
+
+	b := newBranchRules(rules, nil)
+
+	assert.True(t, b.Deletion)
+	assert.True(t, b.NonFastForward)
+	assert.True(t, b.RequiredSignatures)
+	assert.False(t, b.RequiredLinearHistory)
+	assert.Nil(t, b.PullRequest)
+	require.NotNil(t, b.RequiredStatusChecks)
+	assert.Equal(t, RequiredStatusChecks{Contexts: []string{"build", "lint", "test"}, Strict: true}, *b.RequiredStatusChecks)
+	assert.Equal(t, []string{"staging"}, b.RequiredDeployments)
+	assert.Equal(t, []string{".github/workflows/scan.yml"}, b.Workflows)
+	assert.Equal(t, []PatternRule{{Type: "commit_message_pattern", Operator: "starts_with", Pattern: "feat"}}, b.Patterns)
+	assert.Contains(t, b.RuleTypes, "code_scanning")
+	assert.Equal(t, []string{"Organization", "Repository"}, b.Sources)
+}
+
+func TestNewBranchRulesKeepsStrictestPullRequestRule(t *testing.T) {
+	rules := []branchRule{
+		{Type: "pull_request", Parameters: json.RawMessage(`{"required_approving_review_count": 1, "require_code_owner_review": true}`)},
+		{Type: "pull_request", Parameters: json.RawMessage(`{"required_approving_review_count": 3, "dismiss_stale_reviews_on_push": true}`)},
+	}
+
+	b := newBranchRules(rules, nil)
+
+	require.NotNil(t, b.PullRequest)
+	assert.Equal(t, PullRequestRule{RequiredApprovingReviewCount: 3, DismissStaleReviewsOnPush: true, RequireCodeOwnerReview: true}, *b.PullRequest)
+}
+
+func TestNewBranchRulesFromBranchProtection(t *testing.T) {
+	protection := &github.Protection{
+		RequiredPullRequestReviews: &github.PullRequestReviewsEnforcement{
+			RequiredApprovingReviewCount: 2,
+			RequireCodeOwnerReviews:      true,
+		},
+		RequiredConversationResolution: &github.RequiredConversationResolution{Enabled: true},
+		RequiredStatusChecks:           &github.RequiredStatusChecks{Contexts: &[]string{"ci"}},
+		RequireLinearHistory:           &github.RequireLinearHistory{Enabled: true},
+		AllowForcePushes:               &github.AllowForcePushes{Enabled: true},
+	}
+
+	b := newBranchRules(nil, protection)
+
+	require.NotNil(t, b.PullRequest)
+	assert.Equal(t, PullRequestRule{RequiredApprovingReviewCount: 2, RequireCodeOwnerReview: true, RequiredReviewThreadResolution: true}, *b.PullRequest)
+	assert.Equal(t, []string{"ci"}, b.RequiredStatusChecks.Contexts)
+	assert.True(t, b.RequiredLinearHistory)
+	assert.True(t, b.Deletion)
+	assert.False(t, b.NonFastForward)
+	assert.Empty(t, b.RuleTypes)
+	assert.Equal(t, []string{branchProtectionSource}, b.Sources)
+}
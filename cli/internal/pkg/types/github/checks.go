@@ -0,0 +1,145 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gh_foundations/internal/pkg/types"
+	"gh_foundations/internal/pkg/types/policy"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// CheckDefinitions documents every registered check, organization checks first
+func CheckDefinitions() []types.CheckDefinition {
+	definitions := OrganizationChecks.Definitions()
+	definitions = append(definitions, RepositoryChecks.Definitions()...)
+	return append(definitions, TeamChecks.Definitions()...)
+}
+
+type policyEntity interface {
+	policyDocument() (gjson.Result, error)
+	// The policy document fields whose data could not be fetched
+	unavailableFields() fetchErrors
+}
+
+// fetchErrors records why the data of policy document fields could not be fetched, by
+// field path. The rules reading a field, or a field nested in it, are reported as errored
+// instead of being evaluated against the missing data.
+type fetchErrors map[string]error
+
+func (f *fetchErrors) add(field string, err error) {
+	if *f == nil {
+		*f = make(fetchErrors)
+	}
+	(*f)[field] = err
+}
+
+// addIncomplete records that only part of the data of a field could be fetched, the data
+// missing only adding requirements to it. The rules reading the field are evaluated and
+// only reported as errored when the data fetched does not satisfy them.
+func (f *fetchErrors) addIncomplete(field string, err error) {
+	if _, ok := (*f)[field]; ok {
+		return
+	}
+	f.add(field, incompleteError{err})
+}
+
+type incompleteError struct {
+	error
+}
+
+func (e incompleteError) Unwrap() error {
+	return e.error
+}
+
+// Find the error of the field or of the field it is nested in
+func (f fetchErrors) lookup(field string) (string, error) {
+	parts := strings.Split(field, ".")
+	for i := range parts {
+		name := strings.Join(parts[:i+1], ".")
+		if err, ok := f[name]; ok {
+			return name, err
+		}
+	}
+	return "", nil
+}
+
+// ApplyPolicy registers a check for every rule of the policy with the registry of the rule's entity
+func ApplyPolicy(p *policy.Policy) error {
+	var allErrors error
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		var err error
+		switch rule.Entity {
+		case "github_organization":
+			err = registerRule(OrganizationChecks, rule)
+		case "github_repository":
+			err = registerRule(RepositoryChecks, rule)
+		case "github_team":
+			err = registerRule(TeamChecks, rule)
+		default:
+			err = fmt.Errorf("rule %q targets an unknown entity %q", rule.Id, rule.Entity)
+		}
+		allErrors = errors.Join(allErrors, err)
+	}
+	return allErrors
+}
+
+func registerRule[T policyEntity](registry *types.CheckRegistry[T], rule *policy.Rule) error {
+	if _, exists := registry.Get(rule.Id); exists {
+		return fmt.Errorf("rule %q conflicts with an existing %s check", rule.Id, rule.Entity)
+	}
+	registry.Register(types.Check[T]{
+		CheckDefinition: rule.Definition(),
+		Evaluate: func(entity T) error {
+			var incompleteErr error
+			for _, field := range rule.Fields() {
+				name, err := entity.unavailableFields().lookup(field)
+				var incomplete incompleteError
+				if errors.As(err, &incomplete) {
+					incompleteErr = fmt.Errorf("%w: %s could not be fully fetched: %w", types.ErrEvaluation, name, incomplete.error)
+				} else if err != nil {
+					return fmt.Errorf("%w: %s could not be fetched: %w", types.ErrEvaluation, name, err)
+				}
+			}
+			document, err := entity.policyDocument()
+			if err != nil {
+				return fmt.Errorf("%w: %w", types.ErrEvaluation, err)
+			}
+			if err := rule.Evaluate(document); err != nil {
+				if incompleteErr != nil {
+					return incompleteErr
+				}
+				return err
+			}
+			return nil
+		},
+	})
+	return nil
+}
+
+// Build the JSON document policy fields are read from: the entity as returned by the
+// GitHub API, extended with the data fetched separately
+func toPolicyDocument(entity any, extra map[string]any) (gjson.Result, error) {
+	document := make(map[string]any)
+	if entity != nil {
+		bytes, err := json.Marshal(entity)
+		if err != nil {
+			return gjson.Result{}, err
+		}
+		if err := json.Unmarshal(bytes, &document); err != nil {
+			return gjson.Result{}, err
+		}
+	}
+	for key, value := range extra {
+		document[key] = value
+	}
+
+	bytes, err := json.Marshal(document)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	return gjson.ParseBytes(bytes), nil
+}
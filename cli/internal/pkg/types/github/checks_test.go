@@ -0,0 +1,150 @@
+package github
+
+import (
+	"errors"
+	"gh_foundations/internal/pkg/types"
+	"gh_foundations/internal/pkg/types/policy"
+	"testing"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPolicy(t *testing.T) {
+	p, err := policy.Parse([]byte(`
+version: 1
+rules:
+  - id: test_web_commit_signoff_required
+    entity: github_organization
+    type: Department
+    field: web_commit_signoff_required
+    operator: equals
+    expected: true
+  - id: test_roles
+    entity: github_organization
+    type: Department
+    field: custom_repository_roles
+    operator: any
+    conditions:
+      - field: name
+        operator: equals
+        expected: Auditor
+`))
+	require.NoError(t, err)
+	require.NoError(t, ApplyPolicy(p))
+
+	org := &Organization{
+		Organization:          &github.Organization{Login: github.String("octo-org"), WebCommitSignoffRequired: github.Bool(true)},
+		customRepositoryRoles: []github.CustomRepoRoles{{Name: github.String("Auditor")}},
+	}
+	report := org.Check(types.CheckSelection{Include: []string{"test_*"}})
+	assert.Equal(t, map[string]types.CheckResult{"test_web_commit_signoff_required": types.Passed, "test_roles": types.Passed}, report.Checks)
+
+	assert.ErrorContains(t, ApplyPolicy(p), `rule "test_roles" conflicts with an existing github_organization check`)
+}
+
+func TestApplyPolicyUnavailableFields(t *testing.T) {
+	p, err := policy.Parse([]byte(`
+version: 1
+rules:
+  - id: test_fetch_deletion
+    entity: github_repository
+    field: default_branch_rules.deletion
+    operator: equals
+    expected: true
+  - id: test_fetch_visibility
+    entity: github_repository
+    field: visibility
+    operator: equals
+    expected: public
+  - id: test_fetch_workflow_permissions
+    entity: github_repository
+    field: actions.default_workflow_permissions
+    operator: equals
+    expected: read
+  - id: test_fetch_runners
+    entity: github_repository
+    field: actions.self_hosted_runners
+    operator: equals
+    expected: []
+`))
+	require.NoError(t, err)
+	require.NoError(t, ApplyPolicy(p))
+
+	repo := &Repository{slug: "app", Repository: &github.Repository{Name: github.String("app"), Visibility: github.String("public")}}
+	repo.actions.SelfHostedRunners = []string{}
+	repo.fetchErrs.add("default_branch_rules", errors.New("403 Must have admin rights to Repository."))
+	repo.fetchErrs.add("actions.default_workflow_permissions", errors.New("403 Resource not accessible by integration"))
+	report := repo.Check(types.CheckSelection{Include: []string{"test_fetch_*"}})
+
+	assert.Equal(t, map[string]types.CheckResult{
+		"test_fetch_deletion":             types.Errored,
+		"test_fetch_visibility":           types.Passed,
+		"test_fetch_workflow_permissions": types.Errored,
+		"test_fetch_runners":              types.Passed,
+	}, report.Checks)
+	require.Len(t, report.Errors, 1)
+	assert.Empty(t, report.Errors[0].Violations)
+	assert.Equal(t, "check could not be evaluated: default_branch_rules could not be fetched: 403 Must have admin rights to Repository.", report.Errors[0].Errored["test_fetch_deletion"])
+	assert.Equal(t, "check could not be evaluated: actions.default_workflow_permissions could not be fetched: 403 Resource not accessible by integration", report.Errors[0].Errored["test_fetch_workflow_permissions"])
+
+	// Without the branch protection, the rules from the rulesets are evaluated and only
+	// errored when they do not satisfy the check
+	protectionErr := errors.New("403 Must have admin rights to Repository.")
+	repo = &Repository{slug: "app", Repository: &github.Repository{Name: github.String("app")}, branchRules: BranchRules{Deletion: true}}
+	repo.fetchErrs.addIncomplete("default_branch_rules", protectionErr)
+	report = repo.Check(types.CheckSelection{Include: []string{"test_fetch_deletion"}})
+	assert.Equal(t, map[string]types.CheckResult{"test_fetch_deletion": types.Passed}, report.Checks)
+
+	repo.branchRules.Deletion = false
+	report = repo.Check(types.CheckSelection{Include: []string{"test_fetch_deletion"}})
+	assert.Equal(t, map[string]types.CheckResult{"test_fetch_deletion": types.Errored}, report.Checks)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, "check could not be evaluated: default_branch_rules could not be fully fetched: 403 Must have admin rights to Repository.", report.Errors[0].Errored["test_fetch_deletion"])
+
+	// The rules that cannot be read at all stay unavailable
+	repo.fetchErrs = nil
+	repo.fetchErrs.add("default_branch_rules", errors.New("500 Internal Server Error"))
+	repo.fetchErrs.addIncomplete("default_branch_rules", protectionErr)
+	assert.EqualError(t, repo.fetchErrs["default_branch_rules"], "500 Internal Server Error")
+}
+
+func TestApplyPolicyUnknownEntity(t *testing.T) {
+	p, err := policy.Parse([]byte("version: 1\nrules:\n  - id: a\n    entity: github_enterprise\n    field: f\n    operator: exists"))
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, ApplyPolicy(p), `rule "a" targets an unknown entity "github_enterprise"`)
+}
+
+func TestDefaultPolicyCustomRepositoryRoles(t *testing.T) {
+	require.NoError(t, ApplyPolicy(policy.Default()))
+	assert.Len(t, TeamChecks.Definitions(), 4)
+
+	org := &Organization{
+		Organization: &github.Organization{Login: github.String("octo-org")},
+		customRepositoryRoles: []github.CustomRepoRoles{
+			// Permissions are listed in no particular order
+			{Name: github.String("Security Engineer"), BaseRole: github.String("maintain"), Permissions: []string{"write_code_scanning", "delete_alerts_code_scanning"}},
+			{Name: github.String("Contractor"), BaseRole: github.String("write"), Permissions: []string{"manage_webhooks"}},
+		},
+	}
+	report := org.Check(types.CheckSelection{Include: []string{"*_role"}})
+
+	assert.Equal(t, map[string]types.CheckResult{
+		"security_engineer_role": types.Passed,
+		"contractor_role":        types.Passed,
+		"community_manager_role": types.Failed,
+	}, report.Checks)
+
+	// The roles are unknown when the token is not allowed to list them
+	org = &Organization{Organization: &github.Organization{Login: github.String("octo-org")}}
+	org.fetchErrs.add("custom_repository_roles", errors.New("403 Resource not accessible by integration"))
+	report = org.Check(types.CheckSelection{Include: []string{"*_role"}})
+
+	assert.Equal(t, map[string]types.CheckResult{
+		"security_engineer_role": types.Errored,
+		"contractor_role":        types.Errored,
+		"community_manager_role": types.Errored,
+	}, report.Checks)
+}
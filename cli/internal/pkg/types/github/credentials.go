@@ -0,0 +1,227 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+)
+
+// How long before expiry an installation token is replaced
+const tokenRefreshMargin = 5 * time.Minute
+
+// GitHub rejects app JWTs that are valid for more than 10 minutes
+const jwtLifetime = 9 * time.Minute
+
+// ICredentialProvider supplies the token used to authenticate requests to the GitHub API
+type ICredentialProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticTokenProvider always returns the same token, e.g. a personal access token
+type StaticTokenProvider struct {
+	token string
+}
+
+func NewStaticTokenProvider(token string) *StaticTokenProvider {
+	return &StaticTokenProvider{token: token}
+}
+
+func (s *StaticTokenProvider) Token(_ context.Context) (string, error) {
+	return s.token, nil
+}
+
+// AppInstallationTokenProvider authenticates as a GitHub App installation. It signs a
+// JWT with the app's private key, exchanges it for an installation token and refreshes
+// the token shortly before it expires.
+type AppInstallationTokenProvider struct {
+	AppId          int64
+	InstallationId int64
+	privateKey     *rsa.PrivateKey
+	client         *github.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewAppInstallationTokenProvider(appId int64, installationId int64, privateKeyPEM []byte, endpoint Endpoint) (*AppInstallationTokenProvider, error) {
+	key, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &AppInstallationTokenProvider{
+		AppId:          appId,
+		InstallationId: installationId,
+		privateKey:     key,
+	}
+	provider.client, err = endpoint.newClient(&http.Client{
+		Transport: &credentialTransport{credentials: &appJWTProvider{provider}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+func (a *AppInstallationTokenProvider) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Now().Add(tokenRefreshMargin).Before(a.expiresAt) {
+		return a.token, nil
+	}
+
+	installationToken, _, err := a.client.Apps.CreateInstallationToken(ctx, a.InstallationId, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to create an installation token for app %d: %w", a.AppId, err)
+	}
+	a.token = installationToken.GetToken()
+	a.expiresAt = installationToken.GetExpiresAt().Time
+	return a.token, nil
+}
+
+// Sign a short lived JWT identifying the app, as described in
+// https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-json-web-token-jwt-for-a-github-app
+func (a *AppInstallationTokenProvider) signJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		// Backdate the token to allow for clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": fmt.Sprint(a.AppId),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// appJWTProvider authenticates the requests made as the app itself
+type appJWTProvider struct {
+	app *AppInstallationTokenProvider
+}
+
+func (j *appJWTProvider) Token(_ context.Context) (string, error) {
+	return j.app.signJWT(time.Now())
+}
+
+// credentialTransport sets the Authorization header of every request from a credential provider
+type credentialTransport struct {
+	credentials ICredentialProvider
+	base        http.RoundTripper
+}
+
+func (c *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := c.credentials.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	base := c.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+func parsePrivateKey(privateKeyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("unable to decode the GitHub App private key: no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the GitHub App private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// AuthOptions describes the ways the CLI can authenticate with GitHub
+type AuthOptions struct {
+	Token             string
+	AppId             int64
+	AppInstallationId int64
+	AppPrivateKeyPath string
+	// The GitHub instance the credentials are issued by
+	Endpoint Endpoint
+}
+
+// Pick a credential provider from the given options. GitHub App credentials take
+// precedence over a token, and the gh cli is used when neither is given.
+func NewCredentialProvider(opts AuthOptions) (ICredentialProvider, error) {
+	if opts.AppId != 0 || opts.AppInstallationId != 0 || opts.AppPrivateKeyPath != "" {
+		if opts.AppId == 0 || opts.AppInstallationId == 0 || opts.AppPrivateKeyPath == "" {
+			return nil, errors.New("GitHub App authentication requires an app id, an installation id and a private key file")
+		}
+		privateKeyPEM, err := os.ReadFile(opts.AppPrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the GitHub App private key: %w", err)
+		}
+		return NewAppInstallationTokenProvider(opts.AppId, opts.AppInstallationId, privateKeyPEM, opts.Endpoint)
+	}
+
+	if opts.Token != "" {
+		return NewStaticTokenProvider(opts.Token), nil
+	}
+
+	host, err := opts.Endpoint.Host()
+	if err != nil {
+		return nil, err
+	}
+	token, err := getTokenFromGhCli(host)
+	if err != nil {
+		return nil, err
+	}
+	return NewStaticTokenProvider(token), nil
+}
+
+func getTokenFromGhCli(host string) (string, error) {
+	cmd, set := os.LookupEnv("GH_PATH")
+	if !set {
+		cmd = "gh"
+	}
+	out, err := exec.Command(cmd, "auth", "token", "--hostname", host).Output()
+	if err != nil {
+		return "", fmt.Errorf("unable to authenticate with gh cli for host %s", host)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
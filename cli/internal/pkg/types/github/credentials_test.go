@@ -0,0 +1,168 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestCredentialsTestSuite(t *testing.T) {
+	suite.Run(t, new(CredentialsTestSuite))
+}
+
+type CredentialsTestSuite struct {
+	suite.Suite
+	key           *rsa.PrivateKey
+	keyPEM        []byte
+	server        *httptest.Server
+	exchanges     int
+	tokenLifetime time.Duration
+}
+
+func (suite *CredentialsTestSuite) SetupSuite() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(suite.T(), err)
+	suite.key = key
+	suite.keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func (suite *CredentialsTestSuite) SetupTest() {
+	suite.exchanges = 0
+	suite.tokenLifetime = time.Hour
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		claims, err := suite.verifyJWT(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err != nil || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(suite.T(), "7", claims["iss"])
+
+		suite.exchanges++
+		expiresAt := time.Now().Add(suite.tokenLifetime).UTC().Format(time.RFC3339)
+		fmt.Fprintf(w, `{"token": "ghs_%d", "expires_at": %q}`, suite.exchanges, expiresAt)
+	})
+	suite.server = httptest.NewServer(mux)
+}
+
+func (suite *CredentialsTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *CredentialsTestSuite) verifyJWT(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&suite.key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, err
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	claims := make(map[string]any)
+	return claims, json.Unmarshal(payload, &claims)
+}
+
+func (suite *CredentialsTestSuite) newProvider() *AppInstallationTokenProvider {
+	provider, err := NewAppInstallationTokenProvider(7, 42, suite.keyPEM, Endpoint{BaseURL: suite.server.URL})
+	require.NoError(suite.T(), err)
+	return provider
+}
+
+func (suite *CredentialsTestSuite) TestInstallationTokenIsCached() {
+	provider := suite.newProvider()
+
+	first, err := provider.Token(context.Background())
+	require.NoError(suite.T(), err)
+	second, err := provider.Token(context.Background())
+	require.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), "ghs_1", first)
+	assert.Equal(suite.T(), "ghs_1", second)
+	assert.Equal(suite.T(), 1, suite.exchanges)
+}
+
+func (suite *CredentialsTestSuite) TestInstallationTokenIsRefreshedBeforeExpiry() {
+	suite.tokenLifetime = tokenRefreshMargin - time.Minute
+	provider := suite.newProvider()
+
+	first, err := provider.Token(context.Background())
+	require.NoError(suite.T(), err)
+	second, err := provider.Token(context.Background())
+	require.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), "ghs_1", first)
+	assert.Equal(suite.T(), "ghs_2", second)
+}
+
+func (suite *CredentialsTestSuite) TestJWTClaims() {
+	provider := suite.newProvider()
+	now := time.Now()
+
+	token, err := provider.signJWT(now)
+	require.NoError(suite.T(), err)
+	claims, err := suite.verifyJWT(token)
+	require.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), "7", claims["iss"])
+	assert.Equal(suite.T(), float64(now.Add(-time.Minute).Unix()), claims["iat"])
+	assert.Equal(suite.T(), float64(now.Add(jwtLifetime).Unix()), claims["exp"])
+}
+
+func (suite *CredentialsTestSuite) TestPKCS8PrivateKey() {
+	der, err := x509.MarshalPKCS8PrivateKey(suite.key)
+	require.NoError(suite.T(), err)
+
+	_, err = NewAppInstallationTokenProvider(7, 42, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), Endpoint{})
+
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *CredentialsTestSuite) TestInvalidPrivateKey() {
+	_, err := NewAppInstallationTokenProvider(7, 42, []byte("not a key"), Endpoint{})
+
+	assert.Error(suite.T(), err)
+}
+
+func (suite *CredentialsTestSuite) TestNewCredentialProvider() {
+	keyPath := filepath.Join(suite.T().TempDir(), "app.pem")
+	require.NoError(suite.T(), os.WriteFile(keyPath, suite.keyPEM, 0600))
+
+	provider, err := NewCredentialProvider(AuthOptions{Token: "ignored", AppId: 7, AppInstallationId: 42, AppPrivateKeyPath: keyPath})
+	require.NoError(suite.T(), err)
+	assert.IsType(suite.T(), &AppInstallationTokenProvider{}, provider)
+
+	provider, err = NewCredentialProvider(AuthOptions{Token: "ghp_token"})
+	require.NoError(suite.T(), err)
+	token, err := provider.Token(context.Background())
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "ghp_token", token)
+
+	_, err = NewCredentialProvider(AuthOptions{AppId: 7, AppPrivateKeyPath: keyPath})
+	assert.Error(suite.T(), err)
+}
@@ -0,0 +1,111 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v61/github"
+)
+
+const defaultHost = "github.com"
+
+// Endpoint identifies the GitHub instance API calls are sent to: github.com, a
+// GitHub Enterprise Server or a GHE.com tenant with data residency.
+type Endpoint struct {
+	BaseURL   string
+	UploadURL string
+}
+
+// Derive the API endpoint of a GitHub host, as used by the gh cli's GH_HOST
+func EndpointForHost(host string) Endpoint {
+	host = strings.TrimSuffix(strings.ToLower(host), "/")
+	switch {
+	case host == "" || host == defaultHost:
+		return Endpoint{}
+	case strings.HasSuffix(host, ".ghe.com"):
+		return Endpoint{
+			BaseURL:   fmt.Sprintf("https://api.%s/", host),
+			UploadURL: fmt.Sprintf("https://uploads.%s/", host),
+		}
+	default:
+		return Endpoint{
+			BaseURL:   fmt.Sprintf("https://%s/api/v3/", host),
+			UploadURL: fmt.Sprintf("https://%s/api/uploads/", host),
+		}
+	}
+}
+
+// Whether the endpoint is the public github.com API
+func (e Endpoint) IsDefault() bool {
+	return e.BaseURL == ""
+}
+
+// Report the web host of the endpoint, as expected by `gh auth token --hostname`
+func (e Endpoint) Host() (string, error) {
+	if e.IsDefault() {
+		return defaultHost, nil
+	}
+	u, err := url.Parse(e.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid GitHub API URL %q: %w", e.BaseURL, err)
+	}
+	host := u.Hostname()
+	if host == "api."+defaultHost || strings.HasSuffix(host, ".ghe.com") {
+		host = strings.TrimPrefix(host, "api.")
+	}
+	return host, nil
+}
+
+// Create a go-github client that sends its requests to the endpoint
+func (e Endpoint) newClient(httpClient *http.Client) (*github.Client, error) {
+	client := github.NewClient(httpClient)
+	if e.IsDefault() {
+		return client, nil
+	}
+
+	baseURL, err := parseAPIURL(e.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	uploadURL := baseURL
+	if e.UploadURL != "" {
+		uploadURL, err = parseAPIURL(e.UploadURL)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		uploadURL = defaultUploadURL(baseURL)
+	}
+
+	client.BaseURL = baseURL
+	client.UploadURL = uploadURL
+	return client, nil
+}
+
+func parseAPIURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub API URL %q: %w", rawURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid GitHub API URL %q: expected an absolute URL", rawURL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
+
+// Guess the upload URL that goes with an API URL
+func defaultUploadURL(baseURL *url.URL) *url.URL {
+	u := *baseURL
+	switch {
+	case strings.HasPrefix(u.Host, "api."):
+		u.Host = "uploads." + strings.TrimPrefix(u.Host, "api.")
+	case strings.HasSuffix(u.Path, "/api/v3/"):
+		u.Path = strings.TrimSuffix(u.Path, "v3/") + "uploads/"
+	}
+	return &u
+}
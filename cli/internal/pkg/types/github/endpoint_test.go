@@ -0,0 +1,77 @@
+package github
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointForHost(t *testing.T) {
+	tests := map[string]Endpoint{
+		"":                    {},
+		"github.com":          {},
+		"octo.ghe.com":        {BaseURL: "https://api.octo.ghe.com/", UploadURL: "https://uploads.octo.ghe.com/"},
+		"ghes.example.gc.ca":  {BaseURL: "https://ghes.example.gc.ca/api/v3/", UploadURL: "https://ghes.example.gc.ca/api/uploads/"},
+		"GHES.Example.gc.ca/": {BaseURL: "https://ghes.example.gc.ca/api/v3/", UploadURL: "https://ghes.example.gc.ca/api/uploads/"},
+	}
+
+	for host, expected := range tests {
+		assert.Equal(t, expected, EndpointForHost(host), host)
+	}
+}
+
+func TestEndpointHost(t *testing.T) {
+	tests := map[string]string{
+		"":                                  "github.com",
+		"https://api.github.com":            "github.com",
+		"https://api.octo.ghe.com/":         "octo.ghe.com",
+		"https://ghes.example.gc.ca/api/v3": "ghes.example.gc.ca",
+	}
+
+	for baseURL, expected := range tests {
+		host, err := Endpoint{BaseURL: baseURL}.Host()
+		require.NoError(t, err)
+		assert.Equal(t, expected, host, baseURL)
+	}
+}
+
+func TestEndpointNewClient(t *testing.T) {
+	client, err := Endpoint{BaseURL: "https://ghes.example.gc.ca/api/v3"}.newClient(http.DefaultClient)
+	require.NoError(t, err)
+	assert.Equal(t, "https://ghes.example.gc.ca/api/v3/", client.BaseURL.String())
+	assert.Equal(t, "https://ghes.example.gc.ca/api/uploads/", client.UploadURL.String())
+
+	client, err = Endpoint{BaseURL: "https://api.octo.ghe.com"}.newClient(http.DefaultClient)
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.octo.ghe.com/", client.BaseURL.String())
+	assert.Equal(t, "https://uploads.octo.ghe.com/", client.UploadURL.String())
+
+	client, err = Endpoint{BaseURL: "https://ghes.example.gc.ca/api/v3/", UploadURL: "https://uploads.example.gc.ca"}.newClient(http.DefaultClient)
+	require.NoError(t, err)
+	assert.Equal(t, "https://uploads.example.gc.ca/", client.UploadURL.String())
+
+	client, err = Endpoint{}.newClient(http.DefaultClient)
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.github.com/", client.BaseURL.String())
+
+	_, err = Endpoint{BaseURL: "ghes.example.gc.ca"}.newClient(http.DefaultClient)
+	assert.Error(t, err)
+}
+
+func TestGraphqlURL(t *testing.T) {
+	tests := map[string]string{
+		"https://api.github.com/":           "https://api.github.com/graphql",
+		"https://ghes.example.com/api/v3/":  "https://ghes.example.com/api/graphql",
+		"https://api.octocorp.ghe.com/":     "https://api.octocorp.ghe.com/graphql",
+		"https://proxy.example.com/github/": "https://proxy.example.com/github/graphql",
+	}
+
+	for baseURL, expected := range tests {
+		u, err := url.Parse(baseURL)
+		require.NoError(t, err)
+		assert.Equal(t, expected, graphqlURL(u), baseURL)
+	}
+}
@@ -3,24 +3,56 @@ package github
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v61/github"
 )
 
+// The maximum number of repositories whose rules are fetched at the same time
+const maxConcurrentRequests = 10
+
+// The number of results requested per page when listing resources
+const pageSize = 100
+
+// GitHub leaves the organization's security settings out of the responses to the tokens
+// of members that are not owners
+var errOwnersOnly = errors.New("only returned to the organization owners")
+
 type IGithubService interface {
 	GetOrganization(slug string) (Organization, error)
 	GetRepositories(owner string, filterFn func(r Repository) bool) ([]Repository, error)
+	ListRepositories(owner string) ([]Repository, error)
+	GetTeams(owner string) ([]Team, error)
+	GetQuotaUsage() []QuotaUsage
 }
 
 type GithubService struct {
-	client *github.Client
+	client    *github.Client
+	transport *RateLimitTransport
 }
 
-func NewGithubService(authToken string) IGithubService {
-	return &GithubService{
-		client: github.NewClient(nil).WithAuthToken(authToken),
+func NewGithubService(credentials ICredentialProvider, endpoint Endpoint) (IGithubService, error) {
+	// Authenticate every attempt separately so retries after a long wait pick up a refreshed token
+	transport := NewRateLimitTransport(&credentialTransport{credentials: credentials})
+	client, err := endpoint.newClient(&http.Client{Transport: transport})
+	if err != nil {
+		return nil, err
 	}
+	return &GithubService{
+		client:    client,
+		transport: transport,
+	}, nil
+}
+
+// Report the API quota consumed by the service so far
+func (g *GithubService) GetQuotaUsage() []QuotaUsage {
+	return g.transport.Usage()
 }
 
 func (g *GithubService) GetOrganization(slug string) (Organization, error) {
@@ -31,38 +63,521 @@ func (g *GithubService) GetOrganization(slug string) (Organization, error) {
 		return Organization{}, err
 	}
 
+	// The checks of the data that cannot be read are reported as errored
+	var fetchErrs fetchErrors
+	if o.TwoFactorRequirementEnabled == nil {
+		fetchErrs.add("two_factor_requirement_enabled", errOwnersOnly)
+	}
+	if o.DefaultRepoPermission == nil {
+		fetchErrs.add("default_repository_permission", errOwnersOnly)
+	}
+	if o.WebCommitSignoffRequired == nil {
+		fetchErrs.add("web_commit_signoff_required", errOwnersOnly)
+	}
+	roles, err := g.getCustomRepositoryRoles(ctx, slug)
+	if err != nil {
+		fetchErrs.add("custom_repository_roles", err)
+	}
+	owners, err := g.listOrgOwners(ctx, slug)
+	if err != nil {
+		fetchErrs.add("owners", err)
+	}
+	samlSsoEnabled, err := g.getSamlSsoEnabled(ctx, slug)
+	if err != nil {
+		fetchErrs.add("saml_sso_enabled", err)
+	}
+	actions := g.getOrgActionsSettings(ctx, slug, &fetchErrs)
+
 	return Organization{
-		Organization: o,
+		Organization:          o,
+		customRepositoryRoles: roles,
+		owners:                owners,
+		samlSsoEnabled:        samlSsoEnabled,
+		actions:               actions,
+		fetchErrs:             fetchErrs,
 	}, nil
 }
 
+// Read the organization's Actions permissions. Reading them requires the organization
+// administration permission, the settings that cannot be read are left unset and their
+// errors recorded.
+func (g *GithubService) getOrgActionsSettings(ctx context.Context, slug string, fetchErrs *fetchErrors) ActionsSettings {
+	var settings ActionsSettings
+	if permissions, _, err := g.client.Actions.GetActionsPermissions(ctx, slug); err == nil {
+		settings.EnabledRepositories = permissions.EnabledRepositories
+		settings.AllowedActions = permissions.AllowedActions
+	} else {
+		fetchErrs.add("actions.enabled_repositories", err)
+		fetchErrs.add("actions.allowed_actions", err)
+	}
+	if settings.AllowedActions != nil && *settings.AllowedActions == "selected" {
+		if allowed, _, err := g.client.Actions.GetActionsAllowed(ctx, slug); err == nil {
+			settings.SelectedActions = allowed
+		} else {
+			fetchErrs.add("actions.selected_actions", err)
+		}
+	}
+	if workflow, _, err := g.client.Actions.GetDefaultWorkflowPermissionsInOrganization(ctx, slug); err == nil {
+		settings.DefaultWorkflowPermissions = workflow.DefaultWorkflowPermissions
+		settings.CanApprovePullRequestReviews = workflow.CanApprovePullRequestReviews
+	} else {
+		fetchErrs.add("actions.default_workflow_permissions", err)
+		fetchErrs.add("actions.can_approve_pull_request_reviews", err)
+	}
+	policy, err := g.getForkPrApprovalPolicy(ctx, fmt.Sprintf("orgs/%v", slug))
+	if err != nil {
+		fetchErrs.add("actions.fork_pr_approval_policy", err)
+	}
+	settings.ForkPrApprovalPolicy = policy
+
+	groups, err := listAllPages(func(opts github.ListOptions) ([]*github.RunnerGroup, *github.Response, error) {
+		page, resp, err := g.client.Actions.ListOrganizationRunnerGroups(ctx, slug, &github.ListOrgRunnerGroupOptions{ListOptions: opts})
+		if err != nil {
+			return nil, resp, err
+		}
+		return page.RunnerGroups, resp, nil
+	})
+	if err != nil {
+		fetchErrs.add("actions.public_runner_groups", err)
+		return settings
+	}
+	settings.PublicRunnerGroups = []string{}
+	for _, group := range groups {
+		if group.GetAllowsPublicRepositories() {
+			settings.PublicRunnerGroups = append(settings.PublicRunnerGroups, group.GetName())
+		}
+	}
+	return settings
+}
+
+// Read a repository's Actions permissions, and the fork pull request approval policy and
+// self-hosted runners of public repositories, which requires admin access to the repository. The settings that cannot
+// be read are left unset and their errors recorded.
+func (g *GithubService) getRepoActionsSettings(ctx context.Context, owner string, r *github.Repository, fetchErrs *fetchErrors) ActionsSettings {
+	var settings ActionsSettings
+	if permissions, _, err := g.client.Repositories.GetActionsPermissions(ctx, owner, r.GetName()); err == nil {
+		settings.Enabled = permissions.Enabled
+		settings.AllowedActions = permissions.AllowedActions
+	} else {
+		fetchErrs.add("actions.enabled", err)
+		fetchErrs.add("actions.allowed_actions", err)
+	}
+	if settings.AllowedActions != nil && *settings.AllowedActions == "selected" {
+		if allowed, _, err := g.client.Repositories.GetActionsAllowed(ctx, owner, r.GetName()); err == nil {
+			settings.SelectedActions = allowed
+		} else {
+			fetchErrs.add("actions.selected_actions", err)
+		}
+	}
+	if workflow, _, err := g.client.Repositories.GetDefaultWorkflowPermissions(ctx, owner, r.GetName()); err == nil {
+		settings.DefaultWorkflowPermissions = workflow.DefaultWorkflowPermissions
+		settings.CanApprovePullRequestReviews = workflow.CanApprovePullRequestReviews
+	} else {
+		fetchErrs.add("actions.default_workflow_permissions", err)
+		fetchErrs.add("actions.can_approve_pull_request_reviews", err)
+	}
+
+	// Fork pull requests and self-hosted runners are only a concern when anyone can open a pull request
+	if r.GetVisibility() != "public" {
+		return settings
+	}
+	policy, err := g.getForkPrApprovalPolicy(ctx, fmt.Sprintf("repos/%v/%v", owner, r.GetName()))
+	if err != nil {
+		fetchErrs.add("actions.fork_pr_approval_policy", err)
+	}
+	settings.ForkPrApprovalPolicy = policy
+	runners, err := listAllPages(func(opts github.ListOptions) ([]*github.Runner, *github.Response, error) {
+		page, resp, err := g.client.Actions.ListRunners(ctx, owner, r.GetName(), &opts)
+		if err != nil {
+			return nil, resp, err
+		}
+		return page.Runners, resp, nil
+	})
+	if err != nil {
+		fetchErrs.add("actions.self_hosted_runners", err)
+		return settings
+	}
+	settings.SelfHostedRunners = make([]string, len(runners))
+	for i, runner := range runners {
+		settings.SelfHostedRunners[i] = runner.GetName()
+	}
+	return settings
+}
+
+// Read which fork pull requests need an approval before their workflows run, for the
+// organization or repository at the path
+func (g *GithubService) getForkPrApprovalPolicy(ctx context.Context, path string) (*string, error) {
+	req, err := g.client.NewRequest(http.MethodGet, path+"/actions/permissions/fork-pr-contributor-approval", nil)
+	if err != nil {
+		return nil, err
+	}
+	var approval forkPrApproval
+	if _, err := g.client.Do(ctx, req, &approval); err != nil {
+		return nil, err
+	}
+	return approval.ApprovalPolicy, nil
+}
+
+// List the logins of the organization's owners
+func (g *GithubService) listOrgOwners(ctx context.Context, slug string) ([]string, error) {
+	opts := &github.ListMembersOptions{Role: "admin"}
+	users, err := listAllPages(func(listOpts github.ListOptions) ([]*github.User, *github.Response, error) {
+		opts.ListOptions = listOpts
+		return g.client.Organizations.ListMembers(ctx, slug, opts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the owners of %s: %w", slug, err)
+	}
+	return logins(users), nil
+}
+
+// Whether SAML single sign-on is configured for the organization, which is only exposed
+// by the GraphQL API. Returns an error when the token is not allowed to see it.
+func (g *GithubService) getSamlSsoEnabled(ctx context.Context, slug string) (*bool, error) {
+	query := map[string]any{
+		"query":     `query($login: String!) { organization(login: $login) { samlIdentityProvider { id } } }`,
+		"variables": map[string]string{"login": slug},
+	}
+	req, err := g.client.NewRequest(http.MethodPost, graphqlURL(g.client.BaseURL), query)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data struct {
+			Organization *struct {
+				SamlIdentityProvider *struct {
+					Id string `json:"id"`
+				} `json:"samlIdentityProvider"`
+			} `json:"organization"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := g.client.Do(ctx, req, &result); err != nil {
+		return nil, fmt.Errorf("unable to read the SAML settings of %s: %w", slug, err)
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("unable to read the SAML settings of %s: %s", slug, result.Errors[0].Message)
+	}
+	if result.Data.Organization == nil {
+		return nil, fmt.Errorf("unable to read the SAML settings of %s", slug)
+	}
+	enabled := result.Data.Organization.SamlIdentityProvider != nil
+	return &enabled, nil
+}
+
+// The GraphQL endpoint sits next to the REST API: /api/graphql on GitHub Enterprise
+// Server and /graphql elsewhere
+func graphqlURL(baseURL *url.URL) string {
+	u := *baseURL
+	if strings.HasSuffix(u.Path, "/api/v3/") {
+		u.Path = strings.TrimSuffix(u.Path, "v3/") + "graphql"
+	} else {
+		u.Path += "graphql"
+	}
+	return u.String()
+}
+
+// List the organization's custom repository roles. Organizations on plans without
+// custom roles have none. Listing them requires the organization administration
+// permission, without it the roles are unknown and an error is returned.
+func (g *GithubService) getCustomRepositoryRoles(ctx context.Context, slug string) ([]github.CustomRepoRoles, error) {
+	result, resp, err := g.client.Organizations.ListCustomRepoRoles(ctx, slug)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return []github.CustomRepoRoles{}, nil
+		}
+		return nil, fmt.Errorf("unable to list the custom repository roles of %s: %w", slug, err)
+	}
+
+	roles := make([]github.CustomRepoRoles, 0, len(result.CustomRepoRoles))
+	for _, role := range result.CustomRepoRoles {
+		roles = append(roles, *role)
+	}
+	return roles, nil
+}
+
+// GetRepositories fetches the repositories matching the filter along with the data their
+// checks read. The data that cannot be fetched is recorded on the repository, whose checks
+// reading it are then reported as errored. When the deadline is reached, the repositories
+// fetched so far are returned with the error.
 func (g *GithubService) GetRepositories(owner string, filterFn func(r Repository) bool) ([]Repository, error) {
 	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancelFn()
 
-	repos, _, err := g.client.Repositories.ListByUser(ctx, owner, nil)
+	listed, err := g.listOrgRepositories(ctx, owner)
 	if err != nil {
 		return []Repository{}, err
 	}
 
-	var repositories []Repository
+	// Filter before fetching the rules so excluded repositories cost no requests
+	var repos []*github.Repository
+	for _, r := range listed {
+		if filterFn == nil || filterFn(Repository{slug: r.GetName(), Repository: r}) {
+			repos = append(repos, r)
+		}
+	}
 
-	for _, r := range repos {
-		rules, _, err := g.client.Repositories.GetRulesForBranch(ctx, owner, r.GetName(), r.GetDefaultBranch())
-		var rulesets []map[string]interface{}
-		if err == nil {
-			rulesetBytes, err := json.Marshal(rules)
-			if err == nil {
-				json.Unmarshal(rulesetBytes, &rulesets)
+	repositories := make([]Repository, len(repos))
+	fetched := make([]bool, len(repos))
+	ctxErr := forEachConcurrently(ctx, len(repos), func(i int) {
+		repositories[i] = g.getRepository(ctx, owner, repos[i])
+		fetched[i] = true
+	})
+	if ctxErr != nil {
+		var partial []Repository
+		for i, r := range repositories {
+			if fetched[i] {
+				partial = append(partial, r)
 			}
 		}
+		return partial, fmt.Errorf("fetched %d of %d repositories: %w", len(partial), len(repos), ctxErr)
+	}
 
-		repositories = append(repositories, Repository{
-			slug:       r.GetName(),
-			rulesets:   rulesets,
-			Repository: r,
-		})
+	return repositories, nil
+}
+
+// Fetch the rules, branch protection, Actions permissions and outside admin collaborators
+// of a repository
+func (g *GithubService) getRepository(ctx context.Context, owner string, r *github.Repository) Repository {
+	repository := Repository{
+		slug:       r.GetName(),
+		Repository: r,
+	}
+	repository.actions = g.getRepoActionsSettings(ctx, owner, r, &repository.fetchErrs)
+
+	collaborators, err := g.listOutsideAdminCollaborators(ctx, owner, r)
+	if err != nil {
+		repository.fetchErrs.add("outside_admin_collaborators", err)
+	}
+	repository.outsideAdminCollaborators = collaborators
+
+	rules, err := g.getBranchRules(ctx, owner, r)
+	if err != nil {
+		repository.fetchErrs.add("rulesets", err)
+		repository.fetchErrs.add("default_branch_rules", err)
+	}
+	// The rules from the rulesets may satisfy the checks without the branch protection
+	protection, protectionErr := g.getBranchProtection(ctx, owner, r)
+	if protectionErr != nil {
+		repository.fetchErrs.addIncomplete("default_branch_rules", protectionErr)
 	}
+	repository.rulesets = rulesetMaps(rules)
+	repository.branchRules = newBranchRules(rules, protection)
+	return repository
+}
 
+// ListRepositories lists the repositories of the organization with their settings, without
+// the rules and settings GetRepositories fetches for the checks. Every repository is
+// requested on its own, as the listing leaves out settings such as the merge settings.
+// The repositories that cannot be requested keep the settings of the listing.
+func (g *GithubService) ListRepositories(owner string) ([]Repository, error) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancelFn()
+
+	listed, err := g.listOrgRepositories(ctx, owner)
+	if err != nil {
+		return []Repository{}, err
+	}
+	repositories := make([]Repository, len(listed))
+	err = forEachConcurrently(ctx, len(listed), func(i int) {
+		r := listed[i]
+		if full, _, err := g.client.Repositories.Get(ctx, owner, r.GetName()); err == nil {
+			r = full
+		}
+		repositories[i] = Repository{slug: r.GetName(), Repository: r}
+	})
+	if err != nil {
+		return []Repository{}, err
+	}
 	return repositories, nil
 }
+
+// Follow every page of the organization's repository listing
+func (g *GithubService) listOrgRepositories(ctx context.Context, owner string) ([]*github.Repository, error) {
+	opts := &github.RepositoryListByOrgOptions{}
+	return listAllPages(func(listOpts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		opts.ListOptions = listOpts
+		return g.client.Repositories.ListByOrg(ctx, owner, opts)
+	})
+}
+
+// Fetch the rules that apply to a repository's default branch, from the rulesets of the
+// repository and of the organization. The endpoint is requested directly as go-github
+// fails to decode the rule types it does not know about.
+func (g *GithubService) getBranchRules(ctx context.Context, owner string, r *github.Repository) ([]branchRule, error) {
+	u := fmt.Sprintf("repos/%v/%v/rules/branches/%v", owner, r.GetName(), url.PathEscape(r.GetDefaultBranch()))
+	req, err := g.client.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	var rules []branchRule
+	if _, err := g.client.Do(ctx, req, &rules); err != nil {
+		return nil, fmt.Errorf("unable to read the rules of the default branch: %w", err)
+	}
+	return rules, nil
+}
+
+// Fetch the classic protection of a repository's default branch. Returns nil when the
+// branch is not protected, and an error when the protection cannot be read, e.g.
+// without admin access to the repository.
+func (g *GithubService) getBranchProtection(ctx context.Context, owner string, r *github.Repository) (*github.Protection, error) {
+	protection, _, err := g.client.Repositories.GetBranchProtection(ctx, owner, r.GetName(), r.GetDefaultBranch())
+	if errors.Is(err, github.ErrBranchNotProtected) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the protection of the default branch: %w", err)
+	}
+	return protection, nil
+}
+
+// The rules as generic maps, as read by policies through the rulesets field
+func rulesetMaps(rules []branchRule) []map[string]interface{} {
+	var rulesets []map[string]interface{}
+	rulesetBytes, err := json.Marshal(rules)
+	if err == nil {
+		json.Unmarshal(rulesetBytes, &rulesets)
+	}
+	return rulesets
+}
+
+// List the logins of the outside collaborators with admin permissions on a repository.
+// Returns an error when they cannot be listed, e.g. without admin access to the repository.
+func (g *GithubService) listOutsideAdminCollaborators(ctx context.Context, owner string, r *github.Repository) ([]string, error) {
+	opts := &github.ListCollaboratorsOptions{Affiliation: "outside", Permission: "admin"}
+	users, err := listAllPages(func(listOpts github.ListOptions) ([]*github.User, *github.Response, error) {
+		opts.ListOptions = listOpts
+		return g.client.Repositories.ListCollaborators(ctx, owner, r.GetName(), opts)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the outside collaborators: %w", err)
+	}
+	return logins(users), nil
+}
+
+// List the organization's teams along with their maintainers and the repositories they administer
+func (g *GithubService) GetTeams(owner string) ([]Team, error) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancelFn()
+
+	teams, err := listAllPages(func(opts github.ListOptions) ([]*github.Team, *github.Response, error) {
+		return g.client.Teams.ListTeams(ctx, owner, &opts)
+	})
+	if err != nil {
+		return []Team{}, fmt.Errorf("unable to list the teams of %s: %w", owner, err)
+	}
+
+	// The data of a team that cannot be fetched is recorded, so its checks are errored
+	result := make([]Team, len(teams))
+	fetched := make([]bool, len(teams))
+	ctxErr := forEachConcurrently(ctx, len(teams), func(i int) {
+		result[i] = g.getTeam(ctx, owner, teams[i])
+		fetched[i] = true
+	})
+	if ctxErr != nil {
+		var partial []Team
+		for i, t := range result {
+			if fetched[i] {
+				partial = append(partial, t)
+			}
+		}
+		countMaintainedTeams(partial)
+		return partial, fmt.Errorf("fetched %d of %d teams of %s: %w", len(partial), len(teams), owner, ctxErr)
+	}
+	countMaintainedTeams(result)
+	return result, nil
+}
+
+func (g *GithubService) getTeam(ctx context.Context, owner string, t *github.Team) Team {
+	team := Team{
+		Team: t,
+		org:  owner,
+	}
+
+	opts := &github.TeamListTeamMembersOptions{Role: "maintainer"}
+	maintainers, err := listAllPages(func(listOpts github.ListOptions) ([]*github.User, *github.Response, error) {
+		opts.ListOptions = listOpts
+		return g.client.Teams.ListTeamMembersBySlug(ctx, owner, t.GetSlug(), opts)
+	})
+	if err != nil {
+		team.fetchErrs.add("maintainers", fmt.Errorf("unable to list the maintainers of team %s: %w", t.GetSlug(), err))
+	} else {
+		team.maintainers = []teamMaintainer{}
+		for _, login := range logins(maintainers) {
+			team.maintainers = append(team.maintainers, teamMaintainer{Login: login})
+		}
+	}
+
+	repos, err := listAllPages(func(listOpts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		return g.client.Teams.ListTeamReposBySlug(ctx, owner, t.GetSlug(), &listOpts)
+	})
+	if err != nil {
+		team.fetchErrs.add("admin_repositories", fmt.Errorf("unable to list the repositories of team %s: %w", t.GetSlug(), err))
+		return team
+	}
+	team.adminRepositories = []string{}
+	for _, r := range repos {
+		if r.GetPermissions()["admin"] {
+			team.adminRepositories = append(team.adminRepositories, r.GetName())
+		}
+	}
+	return team
+}
+
+// Call fn with every index below n, on up to maxConcurrentRequests goroutines. Stops
+// handing out work as soon as the context is done, waits for the calls in progress and
+// returns the context's error.
+func forEachConcurrently(ctx context.Context, n int, fn func(i int)) error {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(maxConcurrentRequests, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+dispatch:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return ctx.Err()
+}
+
+// Request every page of a listing, using the largest page size
+func listAllPages[T any](list func(opts github.ListOptions) ([]T, *github.Response, error)) ([]T, error) {
+	var items []T
+	opts := github.ListOptions{PerPage: pageSize}
+	for {
+		page, resp, err := list(opts)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+
+		if resp.NextPage == 0 {
+			return items, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func logins(users []*github.User) []string {
+	logins := make([]string, len(users))
+	for i, u := range users {
+		logins[i] = u.GetLogin()
+	}
+	return logins
+}
@@ -0,0 +1,32 @@
+package github
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForEachConcurrently(t *testing.T) {
+	done := make([]bool, 25)
+	err := forEachConcurrently(context.Background(), len(done), func(i int) {
+		done[i] = true
+	})
+
+	assert.NoError(t, err)
+	assert.NotContains(t, done, false)
+}
+
+func TestForEachConcurrentlyStopsWhenTheContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	err := forEachConcurrently(ctx, 100, func(i int) {
+		calls.Add(1)
+		cancel()
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	// The calls already handed out when the context got cancelled still complete
+	assert.LessOrEqual(t, calls.Load(), int32(maxConcurrentRequests+1))
+}
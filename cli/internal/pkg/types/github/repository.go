@@ -1,119 +1,93 @@
 package github
 
 import (
-	"errors"
 	"fmt"
 	"gh_foundations/internal/pkg/types"
-	"reflect"
-	"time"
+	"path"
+	"slices"
+	"strings"
 
 	"github.com/google/go-github/v61/github"
+	"github.com/tidwall/gjson"
 )
 
 type Repository struct {
-	slug     string
-	rulesets []map[string]any
+	slug                      string
+	rulesets                  []map[string]any
+	branchRules               BranchRules
+	outsideAdminCollaborators []string
+	actions                   ActionsSettings
+	fetchErrs                 fetchErrors
 	*github.Repository
 }
 
-func (r *Repository) Check(checkTypes []types.CheckType) types.CheckReport {
-	report := types.CheckReport{
-		EntityType: "github_repository",
-		EntityId:   r.slug,
-		Timestamp:	time.Now().Format(time.RFC3339), // Syslog compliant timestamp
-		Results:    make(map[types.CheckType]types.CheckResult),
-		Errors:     []types.CheckError{},
-	}
-	for _, t := range checkTypes {
-		switch t {
-		case types.GoCGuardrails:
-			r, err := r.GoCGuardrailsCompliant()
-			if err != nil {
-				report.Errors = append(report.Errors, *err)
-			}
-			report.Results[t] = r
-		default:
-			report.Results[t] = types.NotApplicable
-		}
-	}
-	return report
+// RepositoryChecks holds every check run against GitHub repositories. Additional
+// checks can be added with RepositoryChecks.Register.
+var RepositoryChecks = types.NewCheckRegistry[*Repository]("github_repository")
+
+func (r *Repository) Check(selection types.CheckSelection) types.CheckReport {
+	return RepositoryChecks.Run(r.slug, r, selection)
 }
 
-func (r *Repository) GoCGuardrailsCompliant() (types.CheckResult, *types.CheckError) {
-	var allErrors error
-	violations := make(map[string]string)
-	checks := []func(repo *Repository) (string, error) {
-		func(repo *Repository) (string, error) {
-			key := "dependabot_security_updates"
-			if repo.GetSecurityAndAnalysis().GetDependabotSecurityUpdates().GetStatus() != "enabled" {
-				return key, errors.New("dependabot_security_updates is not enabled. Expected it to be enabled")
-			}
-			return key, nil
-		},
-		func(repo *Repository) (string, error) {
-			key := "secret_scanning"
-			if repo.GetSecurityAndAnalysis().GetSecretScanning().GetStatus() != "enabled" {
-				return key, errors.New("secret_scanning is not enabled. Expected it to be enabled")
-			}
-			return key, nil
-		},
-		func(repo *Repository) (string, error) {
-			key := "secret_scanning_push_protection"
-			if repo.GetSecurityAndAnalysis().GetSecretScanningPushProtection().GetStatus() != "enabled" {
-				return key, errors.New("secret_scanning_push_protection is not enabled. Expected it to be enabled")
-			}
-			return key, nil
-		},
-		func(repo *Repository) (string, error) {
-			key := "delete_branch_on_merge"
-			if !repo.GetDeleteBranchOnMerge() {
-				return key, errors.New("delete_branch_on_merge is not enabled. Expected it to be enabled")
-			}
-			return key, nil
-		},
-		func(repo *Repository) (string, error) {
-			key := "rulesets"
-			var errs error
-			expectedRulesets := []map[string]any{
-				{
-					"type": "pull_request",
-					"parameters": map[string]any{
-						"required_approving_review_count":   1,
-						"dismiss_stale_reviews_on_push":     true,
-						"require_code_owner_review":         false,
-						"require_last_push_approval":        true,
-						"required_review_thread_resolution": false,
-					},
-					"ruleset_source_type": "Repository",
-				},
-			}
+// The repository settings read by policy rules, along with the rules that apply to its
+// default branch, as listed and as merged with its branch protection, the logins of the
+// outside collaborators with admin permissions and its Actions permissions
+func (r *Repository) policyDocument() (gjson.Result, error) {
+	return toPolicyDocument(r.Repository, map[string]any{
+		"actions":                     r.actions,
+		"rulesets":                    r.rulesets,
+		"default_branch_rules":        r.branchRules,
+		"outside_admin_collaborators": r.outsideAdminCollaborators,
+	})
+}
 
-			for _, ruleset := range expectedRulesets {
-				eq := reflect.DeepEqual(ruleset, r.rulesets)
-				if !eq {
-					errMsg := fmt.Sprintf("expected ruleset %q to be %q. Got %q instead", ruleset, fmt.Sprint(ruleset), fmt.Sprint(r.rulesets))
-					violations["rulesets"] = errMsg
-					errs = errors.Join(errs, errors.New(errMsg))
-				}
-			}
-			return key, errs
-		},
-	}
+func (r *Repository) unavailableFields() fetchErrors {
+	return r.fetchErrs
+}
 
-	for _, check := range checks {
-		violationKey, err := check(r)
-		if err != nil {
-			violations[violationKey] = err.Error()
+// RepositoryFilter selects the repositories to check. Empty criteria match every repository.
+type RepositoryFilter struct {
+	// Repository names, shell globs such as "app-*" are allowed
+	Names []string
+	// Repositories with at least one of the topics
+	Topics []string
+	// public, private or internal
+	Visibility string
+	// Only the repositories whose lower cased names are in the set, when it is not nil
+	Managed map[string]bool
+}
+
+var repositoryVisibilities = []string{"public", "private", "internal"}
+
+func (f RepositoryFilter) Validate() error {
+	if f.Visibility != "" && !slices.Contains(repositoryVisibilities, f.Visibility) {
+		return fmt.Errorf("unknown visibility %q, expected one of %s", f.Visibility, strings.Join(repositoryVisibilities, "|"))
+	}
+	for _, name := range f.Names {
+		if _, err := path.Match(name, ""); err != nil {
+			return fmt.Errorf("invalid repository pattern %q: %w", name, err)
 		}
-		allErrors = errors.Join(allErrors, err)
 	}
+	return nil
+}
 
-	if allErrors != nil {
-		return types.Failed, &types.CheckError{
-			Err:        allErrors,
-			Check:      types.GoCGuardrails,
-			Violations: violations,
-		}
+// Matches reports whether the repository meets every criteria of the filter. Names
+// are compared case insensitively, as GitHub does.
+func (f RepositoryFilter) Matches(r Repository) bool {
+	name := strings.ToLower(r.GetName())
+	if len(f.Names) > 0 && !slices.ContainsFunc(f.Names, func(pattern string) bool {
+		matched, _ := path.Match(strings.ToLower(pattern), name)
+		return matched
+	}) {
+		return false
+	}
+	if len(f.Topics) > 0 && !slices.ContainsFunc(r.Topics, func(topic string) bool {
+		return slices.Contains(f.Topics, topic)
+	}) {
+		return false
+	}
+	if f.Visibility != "" && r.GetVisibility() != f.Visibility {
+		return false
 	}
-	return types.Passed, nil
+	return f.Managed == nil || f.Managed[name]
 }
@@ -0,0 +1,47 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryFilterMatches(t *testing.T) {
+	repo := Repository{
+		slug: "App-Frontend",
+		Repository: &github.Repository{
+			Name:       github.String("App-Frontend"),
+			Topics:     []string{"web", "team-a"},
+			Visibility: github.String("internal"),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		filter  RepositoryFilter
+		matches bool
+	}{
+		{"empty", RepositoryFilter{}, true},
+		{"name", RepositoryFilter{Names: []string{"app-frontend"}}, true},
+		{"glob", RepositoryFilter{Names: []string{"other", "app-*"}}, true},
+		{"other name", RepositoryFilter{Names: []string{"app"}}, false},
+		{"topic", RepositoryFilter{Topics: []string{"api", "web"}}, true},
+		{"other topic", RepositoryFilter{Topics: []string{"api"}}, false},
+		{"visibility", RepositoryFilter{Visibility: "internal"}, true},
+		{"other visibility", RepositoryFilter{Visibility: "public"}, false},
+		{"managed", RepositoryFilter{Managed: map[string]bool{"app-frontend": true}}, true},
+		{"not managed", RepositoryFilter{Managed: map[string]bool{}}, false},
+		{"all criteria", RepositoryFilter{Names: []string{"app-*"}, Topics: []string{"web"}, Visibility: "public"}, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.matches, test.filter.Matches(repo), test.name)
+	}
+}
+
+func TestRepositoryFilterValidate(t *testing.T) {
+	assert.NoError(t, RepositoryFilter{Names: []string{"app-*"}, Visibility: "private"}.Validate())
+	assert.EqualError(t, RepositoryFilter{Visibility: "secret"}.Validate(), `unknown visibility "secret", expected one of public|private|internal`)
+	assert.ErrorContains(t, RepositoryFilter{Names: []string{"app-["}}.Validate(), `invalid repository pattern "app-["`)
+}
@@ -0,0 +1,69 @@
+package github
+
+import (
+	"fmt"
+	"gh_foundations/internal/pkg/types"
+	"strings"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/tidwall/gjson"
+)
+
+type Team struct {
+	*github.Team
+	org               string
+	maintainers       []teamMaintainer
+	adminRepositories []string
+	fetchErrs         fetchErrors
+}
+
+type teamMaintainer struct {
+	Login string `json:"login"`
+	// The number of teams of the organization the user maintains
+	MaintainedTeams int `json:"maintained_teams"`
+}
+
+// TeamChecks holds every check run against GitHub teams. Additional checks can be
+// added with TeamChecks.Register.
+var TeamChecks = types.NewCheckRegistry[*Team]("github_team")
+
+func (t *Team) Check(selection types.CheckSelection) types.CheckReport {
+	return TeamChecks.Run(fmt.Sprintf("%s/%s", t.org, t.GetSlug()), t, selection)
+}
+
+// The team settings read by policy rules, along with its maintainers and the names of
+// the repositories it administers
+func (t *Team) policyDocument() (gjson.Result, error) {
+	return toPolicyDocument(t.Team, map[string]any{
+		"maintainers":        t.maintainers,
+		"admin_repositories": t.adminRepositories,
+	})
+}
+
+func (t *Team) unavailableFields() fetchErrors {
+	return t.fetchErrs
+}
+
+// Record on every maintainer how many of the teams they maintain. The teams maintained
+// cannot be counted when the maintainers of one of the teams could not be fetched.
+func countMaintainedTeams(teams []Team) {
+	counts := make(map[string]int)
+	var uncounted []string
+	for _, t := range teams {
+		if _, ok := t.fetchErrs["maintainers"]; ok {
+			uncounted = append(uncounted, t.GetSlug())
+		}
+		for _, m := range t.maintainers {
+			counts[m.Login]++
+		}
+	}
+	for i := range teams {
+		t := &teams[i]
+		if len(uncounted) > 0 {
+			t.fetchErrs.add("maintainers.maintained_teams", fmt.Errorf("the maintainers of %s could not be fetched", strings.Join(uncounted, ", ")))
+		}
+		for j := range t.maintainers {
+			t.maintainers[j].MaintainedTeams = counts[t.maintainers[j].Login]
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	headerRateLimit     = "X-RateLimit-Limit"
+	headerRateRemaining = "X-RateLimit-Remaining"
+	headerRateReset     = "X-RateLimit-Reset"
+	headerRateResource  = "X-RateLimit-Resource"
+	headerRetryAfter    = "Retry-After"
+)
+
+const (
+	defaultMaxRetries              = 5
+	defaultMaxWait                 = 15 * time.Minute
+	defaultBaseDelay               = time.Second
+	defaultSecondaryRateLimitDelay = time.Minute
+)
+
+// QuotaUsage records how many requests were made against a GitHub rate limit
+// resource (core, search, graphql, ...) and the last quota state reported for it.
+type QuotaUsage struct {
+	Resource  string
+	Requests  int
+	Retries   int
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimitTransport is an http.RoundTripper that keeps a client within GitHub's
+// primary and secondary rate limits. It holds the requests back until the quota resets
+// once it is exhausted, honours Retry-After, backs off with jitter on server errors and
+// retries idempotent requests.
+type RateLimitTransport struct {
+	Base http.RoundTripper
+	// The maximum number of times a single request is retried
+	MaxRetries int
+	// The longest single wait the transport accepts before giving up
+	MaxWait time.Duration
+	// The initial backoff used for server errors, doubled on every attempt
+	BaseDelay time.Duration
+	// The wait used for secondary rate limits that do not send Retry-After
+	SecondaryRateLimitDelay time.Duration
+
+	// sleep function for mocking
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu    sync.Mutex
+	usage map[string]*QuotaUsage
+	// When the requests to a rate limit resource can be sent again, once a response
+	// used up the last of its quota
+	resumeAt map[string]time.Time
+}
+
+func NewRateLimitTransport(base http.RoundTripper) *RateLimitTransport {
+	return &RateLimitTransport{
+		Base:                    base,
+		MaxRetries:              defaultMaxRetries,
+		MaxWait:                 defaultMaxWait,
+		BaseDelay:               defaultBaseDelay,
+		SecondaryRateLimitDelay: defaultSecondaryRateLimitDelay,
+		sleep:                   sleepContext,
+		usage:                   make(map[string]*QuotaUsage),
+		resumeAt:                make(map[string]time.Time),
+	}
+}
+
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		outReq, err := rewindRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+		// An earlier request used up the last of the quota, wait for the reset so this
+		// request is not rejected
+		if wait := t.resumeDelay(req); wait > 0 && wait <= t.MaxWait {
+			if err := t.sleep(req.Context(), wait); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := t.base().RoundTrip(outReq)
+		canRetry := attempt < t.MaxRetries && isIdempotent(req)
+		if err != nil {
+			if !canRetry || req.Context().Err() != nil {
+				return nil, err
+			}
+			if err := t.sleep(req.Context(), t.backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		t.record(resp, attempt > 0)
+
+		wait, retry := t.retryDelay(resp, attempt)
+		if !retry || !canRetry || wait > t.MaxWait {
+			// The request succeeded but used up the last of the quota, the next requests
+			// wait for the reset
+			if wait, exhausted := primaryResetDelay(resp); exhausted && resp.StatusCode < 400 {
+				t.pause(resp, wait)
+			}
+			return resp, nil
+		}
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Usage returns the quota consumed so far for each rate limit resource, sorted by resource name
+func (t *RateLimitTransport) Usage() []QuotaUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	usage := make([]QuotaUsage, 0, len(t.usage))
+	for _, u := range t.usage {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Resource < usage[j].Resource })
+	return usage
+}
+
+func (t *RateLimitTransport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// Hold back the requests to the resource of the response for the duration
+func (t *RateLimitTransport) pause(resp *http.Response, d time.Duration) {
+	resource := resp.Header.Get(headerRateResource)
+	if resource == "" {
+		resource = "core"
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resumeAt[resource] = time.Now().Add(d)
+}
+
+// Report how long the request must wait for the quota of its resource to reset
+func (t *RateLimitTransport) resumeDelay(req *http.Request) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Until(t.resumeAt[requestResource(req)])
+}
+
+// The rate limit resource a request counts against. Only the resources the service
+// requests are told apart, the others are counted against core.
+func requestResource(req *http.Request) string {
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/graphql"):
+		return "graphql"
+	case strings.Contains(req.URL.Path, "/search/"):
+		return "search"
+	default:
+		return "core"
+	}
+}
+
+func (t *RateLimitTransport) record(resp *http.Response, retried bool) {
+	limit, err := strconv.Atoi(resp.Header.Get(headerRateLimit))
+	if err != nil {
+		return
+	}
+	resource := resp.Header.Get(headerRateResource)
+	if resource == "" {
+		resource = "core"
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.usage[resource]
+	if !ok {
+		u = &QuotaUsage{Resource: resource}
+		t.usage[resource] = u
+	}
+	u.Requests++
+	if retried {
+		u.Retries++
+	}
+	u.Limit = limit
+	u.Remaining, _ = strconv.Atoi(resp.Header.Get(headerRateRemaining))
+	if reset, err := strconv.ParseInt(resp.Header.Get(headerRateReset), 10, 64); err == nil {
+		u.Reset = time.Unix(reset, 0)
+	}
+}
+
+// Determine whether a response should be retried and how long to wait before doing so
+func (t *RateLimitTransport) retryDelay(resp *http.Response, attempt int) (time.Duration, bool) {
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if wait, ok := retryAfter(resp); ok {
+			return wait, true
+		}
+		if wait, exhausted := primaryResetDelay(resp); exhausted {
+			return wait, true
+		}
+		if resp.StatusCode == http.StatusTooManyRequests || isSecondaryRateLimit(resp) {
+			return t.SecondaryRateLimitDelay + t.jitter(t.SecondaryRateLimitDelay), true
+		}
+		return 0, false
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return t.backoff(attempt), true
+	default:
+		return 0, false
+	}
+}
+
+// Exponential backoff with up to 50% jitter
+func (t *RateLimitTransport) backoff(attempt int) time.Duration {
+	delay := t.BaseDelay << attempt
+	return delay + t.jitter(delay)
+}
+
+func (t *RateLimitTransport) jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d / 2)))
+}
+
+// Read the Retry-After header, given either as a number of seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get(headerRetryAfter)
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	return max(time.Until(date), 0), true
+}
+
+// Report the time left until the primary rate limit resets, if the response says it is exhausted
+func primaryResetDelay(resp *http.Response) (time.Duration, bool) {
+	if resp.Header.Get(headerRateRemaining) != "0" {
+		return 0, false
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get(headerRateReset), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	// Leave a second of slack for clock skew between us and GitHub
+	wait := time.Until(time.Unix(reset, 0)) + time.Second
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
+
+// Secondary rate limits are reported as a 403 whose body mentions them. The body is
+// buffered so it can still be read by the caller.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	default:
+		return false
+	}
+}
+
+// Produce a copy of the request with a fresh body for every retry
+func rewindRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	outReq := req.Clone(req.Context())
+	outReq.Body = body
+	return outReq, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,671 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRateLimitTransportTestSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitTransportTestSuite))
+}
+
+type RateLimitTransportTestSuite struct {
+	suite.Suite
+	mux       *http.ServeMux
+	server    *httptest.Server
+	transport *RateLimitTransport
+	client    *http.Client
+	sleeps    []time.Duration
+	mu        sync.Mutex
+}
+
+func (suite *RateLimitTransportTestSuite) SetupTest() {
+	suite.mux = http.NewServeMux()
+	suite.server = httptest.NewServer(suite.mux)
+	suite.sleeps = nil
+	suite.transport = NewRateLimitTransport(nil)
+	suite.transport.sleep = func(_ context.Context, d time.Duration) error {
+		suite.mu.Lock()
+		defer suite.mu.Unlock()
+		suite.sleeps = append(suite.sleeps, d)
+		return nil
+	}
+	suite.client = &http.Client{Transport: suite.transport}
+}
+
+func (suite *RateLimitTransportTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+// Register a handler that replies with each of the given handlers in turn
+func (suite *RateLimitTransportTestSuite) handleSequence(pattern string, handlers ...http.HandlerFunc) *int {
+	calls := 0
+	suite.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		handler := handlers[min(calls, len(handlers)-1)]
+		calls++
+		handler(w, r)
+	})
+	return &calls
+}
+
+func rateHeaders(w http.ResponseWriter, remaining int, reset time.Time) {
+	w.Header().Set(headerRateLimit, "5000")
+	w.Header().Set(headerRateRemaining, fmt.Sprint(remaining))
+	w.Header().Set(headerRateReset, fmt.Sprint(reset.Unix()))
+	w.Header().Set(headerRateResource, "core")
+}
+
+func ok(w http.ResponseWriter, _ *http.Request) {
+	rateHeaders(w, 4999, time.Now().Add(time.Hour))
+	w.Write([]byte(`{}`))
+}
+
+func (suite *RateLimitTransportTestSuite) get(path string) *http.Response {
+	resp, err := suite.client.Get(suite.server.URL + path)
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+	return resp
+}
+
+func (suite *RateLimitTransportTestSuite) TestRetryAfterSecondaryRateLimit() {
+	calls := suite.handleSequence("/limited", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(headerRetryAfter, "3")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+	}, ok)
+
+	resp := suite.get("/limited")
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), 2, *calls)
+	assert.Equal(suite.T(), []time.Duration{3 * time.Second}, suite.sleeps)
+}
+
+func (suite *RateLimitTransportTestSuite) TestRetryAfterDate() {
+	retryAt := time.Now().Add(20 * time.Second)
+	calls := suite.handleSequence("/limited", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(headerRetryAfter, retryAt.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusTooManyRequests)
+	}, ok)
+
+	resp := suite.get("/limited")
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), 2, *calls)
+	require.Len(suite.T(), suite.sleeps, 1)
+	assert.InDelta(suite.T(), float64(20*time.Second), float64(suite.sleeps[0]), float64(2*time.Second))
+}
+
+func (suite *RateLimitTransportTestSuite) TestSecondaryRateLimitWithoutRetryAfter() {
+	calls := suite.handleSequence("/limited", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+	}, ok)
+
+	resp := suite.get("/limited")
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), 2, *calls)
+	require.Len(suite.T(), suite.sleeps, 1)
+	assert.GreaterOrEqual(suite.T(), suite.sleeps[0], defaultSecondaryRateLimitDelay)
+	assert.Less(suite.T(), suite.sleeps[0], defaultSecondaryRateLimitDelay*3/2)
+}
+
+func (suite *RateLimitTransportTestSuite) TestPrimaryRateLimitExhausted() {
+	reset := time.Now().Add(30 * time.Second)
+	calls := suite.handleSequence("/limited", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 0, reset)
+		w.WriteHeader(http.StatusForbidden)
+	}, ok)
+
+	resp := suite.get("/limited")
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), 2, *calls)
+	require.Len(suite.T(), suite.sleeps, 1)
+	assert.InDelta(suite.T(), float64(31*time.Second), float64(suite.sleeps[0]), float64(2*time.Second))
+}
+
+func (suite *RateLimitTransportTestSuite) TestPrimaryRateLimitUsedUpBySuccessfulRequest() {
+	reset := time.Now().Add(10 * time.Second)
+	calls := suite.handleSequence("/last", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 0, reset)
+		w.Write([]byte(`{}`))
+	})
+
+	suite.mux.HandleFunc("/search/code", ok)
+
+	// The response is returned right away, the next request waits for the reset
+	resp := suite.get("/last")
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Empty(suite.T(), suite.sleeps)
+
+	// The requests counted against another resource are not held back
+	suite.get("/search/code")
+	assert.Empty(suite.T(), suite.sleeps)
+
+	resp = suite.get("/last")
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), 2, *calls)
+	require.Len(suite.T(), suite.sleeps, 1)
+	assert.InDelta(suite.T(), float64(11*time.Second), float64(suite.sleeps[0]), float64(2*time.Second))
+}
+
+func (suite *RateLimitTransportTestSuite) TestPrimaryRateLimitResetTooFarAway() {
+	reset := time.Now().Add(2 * time.Hour)
+	calls := suite.handleSequence("/limited", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 0, reset)
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	resp := suite.get("/limited")
+
+	assert.Equal(suite.T(), http.StatusForbidden, resp.StatusCode)
+	assert.Equal(suite.T(), 1, *calls)
+	assert.Empty(suite.T(), suite.sleeps)
+}
+
+func (suite *RateLimitTransportTestSuite) TestServerErrorBackoff() {
+	calls := suite.handleSequence("/flaky", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, ok)
+
+	resp := suite.get("/flaky")
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), 3, *calls)
+	require.Len(suite.T(), suite.sleeps, 2)
+	assert.GreaterOrEqual(suite.T(), suite.sleeps[0], defaultBaseDelay)
+	assert.Less(suite.T(), suite.sleeps[0], defaultBaseDelay*3/2)
+	assert.GreaterOrEqual(suite.T(), suite.sleeps[1], 2*defaultBaseDelay)
+	assert.Less(suite.T(), suite.sleeps[1], 3*defaultBaseDelay)
+}
+
+func (suite *RateLimitTransportTestSuite) TestGivesUpAfterMaxRetries() {
+	suite.transport.MaxRetries = 2
+	calls := suite.handleSequence("/down", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	resp := suite.get("/down")
+
+	assert.Equal(suite.T(), http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(suite.T(), 3, *calls)
+	assert.Len(suite.T(), suite.sleeps, 2)
+}
+
+func (suite *RateLimitTransportTestSuite) TestNonIdempotentRequestsAreNotRetried() {
+	calls := suite.handleSequence("/create", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}, ok)
+
+	resp, err := suite.client.Post(suite.server.URL+"/create", "application/json", strings.NewReader(`{}`))
+	require.NoError(suite.T(), err)
+	resp.Body.Close()
+
+	assert.Equal(suite.T(), http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(suite.T(), 1, *calls)
+	assert.Empty(suite.T(), suite.sleeps)
+}
+
+func (suite *RateLimitTransportTestSuite) TestPermissionErrorsAreNotRetried() {
+	calls := suite.handleSequence("/forbidden", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "Resource not accessible by integration"}`))
+	})
+
+	resp := suite.get("/forbidden")
+
+	assert.Equal(suite.T(), http.StatusForbidden, resp.StatusCode)
+	assert.Equal(suite.T(), 1, *calls)
+	assert.Empty(suite.T(), suite.sleeps)
+}
+
+func (suite *RateLimitTransportTestSuite) TestUsageIsRecorded() {
+	suite.handleSequence("/flaky", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4999, time.Now().Add(time.Hour))
+		w.WriteHeader(http.StatusBadGateway)
+	}, ok)
+	suite.handleSequence("/search", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 29, time.Now().Add(time.Minute))
+		w.Header().Set(headerRateResource, "search")
+		w.Write([]byte(`{}`))
+	})
+
+	suite.get("/flaky")
+	suite.get("/search")
+
+	usage := suite.transport.Usage()
+	require.Len(suite.T(), usage, 2)
+	assert.Equal(suite.T(), "core", usage[0].Resource)
+	assert.Equal(suite.T(), 2, usage[0].Requests)
+	assert.Equal(suite.T(), 1, usage[0].Retries)
+	assert.Equal(suite.T(), 5000, usage[0].Limit)
+	assert.Equal(suite.T(), "search", usage[1].Resource)
+	assert.Equal(suite.T(), 1, usage[1].Requests)
+	assert.Equal(suite.T(), 29, usage[1].Remaining)
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetRepositoriesFollowsPages() {
+	pages := map[string][]string{
+		"":  {"repo-1", "repo-2"},
+		"2": {"repo-3"},
+	}
+	suite.mux.HandleFunc("/orgs/org/repos", func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/org/repos?page=2>; rel="next"`, suite.server.URL))
+		}
+		var repos []string
+		for _, name := range pages[page] {
+			repos = append(repos, fmt.Sprintf(`{"name": %q, "default_branch": "main"}`, name))
+		}
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		fmt.Fprintf(w, "[%s]", strings.Join(repos, ","))
+	})
+	suite.mux.HandleFunc("/repos/org/", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[{"type": "deletion"}]`))
+	})
+
+	service := suite.newGithubService()
+	repos, err := service.GetRepositories("org", nil)
+
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), repos, 3)
+	for i, r := range repos {
+		assert.Equal(suite.T(), fmt.Sprintf("repo-%d", i+1), r.slug)
+		require.Len(suite.T(), r.rulesets, 1)
+		assert.Equal(suite.T(), "deletion", r.rulesets[0]["type"])
+	}
+	// Two pages, then the rules, the branch protection, the outside admin collaborators and
+	// the two Actions permissions of every private repository
+	assert.Equal(suite.T(), 17, service.GetQuotaUsage()[0].Requests)
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetRepositoriesFiltersBeforeFetchingRules() {
+	suite.mux.HandleFunc("/orgs/org/repos", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[{"name": "app", "visibility": "public", "default_branch": "main"}, {"name": "infra", "visibility": "private", "default_branch": "main"}]`))
+	})
+	var fetched []string
+	suite.mux.HandleFunc("/repos/org/", func(w http.ResponseWriter, r *http.Request) {
+		fetched = append(fetched, r.URL.Path)
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[]`))
+	})
+
+	service := suite.newGithubService()
+	repos, err := service.GetRepositories("org", RepositoryFilter{Visibility: "private"}.Matches)
+
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), repos, 1)
+	assert.Equal(suite.T(), "infra", repos[0].slug)
+	assert.ElementsMatch(suite.T(), []string{
+		"/repos/org/infra/rules/branches/main", "/repos/org/infra/branches/main/protection", "/repos/org/infra/collaborators",
+		"/repos/org/infra/actions/permissions", "/repos/org/infra/actions/permissions/workflow",
+	}, fetched)
+}
+
+func (suite *RateLimitTransportTestSuite) TestListRepositoriesRequestsEveryRepository() {
+	suite.mux.HandleFunc("/orgs/org/repos", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[{"name": "app", "default_branch": "main"}, {"name": "infra", "default_branch": "main"}]`))
+	})
+	suite.mux.HandleFunc("/repos/org/", func(w http.ResponseWriter, r *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		switch r.URL.Path {
+		case "/repos/org/app":
+			w.Write([]byte(`{"name": "app", "default_branch": "main", "delete_branch_on_merge": true, "web_commit_signoff_required": false}`))
+		case "/repos/org/infra":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			suite.T().Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	service := suite.newGithubService()
+	repos, err := service.ListRepositories("org")
+
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), repos, 2)
+	assert.True(suite.T(), repos[0].GetDeleteBranchOnMerge())
+	assert.NotNil(suite.T(), repos[0].WebCommitSignoffRequired)
+	// The listing is kept when the repository cannot be requested
+	assert.Equal(suite.T(), "infra", repos[1].slug)
+	assert.Equal(suite.T(), "main", repos[1].GetDefaultBranch())
+	assert.Nil(suite.T(), repos[1].DeleteBranchOnMerge)
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetRepositoriesMergesBranchRules() {
+	suite.mux.HandleFunc("/orgs/org/repos", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[{"name": "app", "default_branch": "main"}]`))
+	})
+	suite.mux.HandleFunc("/repos/org/app/rules/branches/main", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[
+			{"type": "pull_request", "ruleset_source_type": "Organization", "ruleset_source": "org", "ruleset_id": 1,
+			 "parameters": {"required_approving_review_count": 2, "dismiss_stale_reviews_on_push": true}},
+			{"type": "merge_queue", "ruleset_source_type": "Repository", "ruleset_source": "org/app", "ruleset_id": 2,
+			 "parameters": {"merge_method": "SQUASH"}}
+		]`))
+	})
+	suite.mux.HandleFunc("/repos/org/app/branches/main/protection", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`{"required_pull_request_reviews": {"required_approving_review_count": 1, "require_last_push_approval": true},
+			"allow_force_pushes": {"enabled": false}, "allow_deletions": {"enabled": true}}`))
+	})
+	suite.mux.HandleFunc("/repos/org/app/collaborators", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[]`))
+	})
+
+	repos, err := suite.newGithubService().GetRepositories("org", nil)
+
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), repos, 1)
+	rules := repos[0].branchRules
+	require.NotNil(suite.T(), rules.PullRequest)
+	assert.Equal(suite.T(), PullRequestRule{RequiredApprovingReviewCount: 2, DismissStaleReviewsOnPush: true, RequireLastPushApproval: true}, *rules.PullRequest)
+	assert.True(suite.T(), rules.NonFastForward)
+	assert.False(suite.T(), rules.Deletion)
+	assert.Equal(suite.T(), []string{"merge_queue", "pull_request"}, rules.RuleTypes)
+	assert.Equal(suite.T(), []string{"BranchProtection", "Organization", "Repository"}, rules.Sources)
+	assert.Len(suite.T(), repos[0].rulesets, 2)
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetRepositoriesRecordsFetchErrors() {
+	suite.mux.HandleFunc("/orgs/org/repos", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[{"name": "app", "default_branch": "main"}, {"name": "infra", "default_branch": "main"}]`))
+	})
+	suite.mux.HandleFunc("/repos/org/", func(w http.ResponseWriter, r *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		switch r.URL.Path {
+		case "/repos/org/app/branches/main/protection":
+			http.Error(w, `{"message": "Must have admin rights to Repository."}`, http.StatusForbidden)
+		case "/repos/org/infra/branches/main/protection":
+			http.Error(w, `{"message": "Branch not protected"}`, http.StatusNotFound)
+		case "/repos/org/app/actions/permissions", "/repos/org/infra/actions/permissions",
+			"/repos/org/app/actions/permissions/workflow", "/repos/org/infra/actions/permissions/workflow":
+			w.Write([]byte(`{}`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	})
+
+	repos, err := suite.newGithubService().GetRepositories("org", nil)
+
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), repos, 2)
+	assert.ErrorContains(suite.T(), repos[0].fetchErrs["default_branch_rules"], "Must have admin rights to Repository.")
+	assert.NotContains(suite.T(), repos[0].fetchErrs, "rulesets")
+	assert.Empty(suite.T(), repos[1].fetchErrs)
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetRepositoriesActionsSettings() {
+	suite.mux.HandleFunc("/orgs/org/repos", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[{"name": "site", "visibility": "public", "default_branch": "main"}]`))
+	})
+	responses := map[string]string{
+		"/repos/org/site/actions/permissions":                              `{"enabled": true, "allowed_actions": "selected"}`,
+		"/repos/org/site/actions/permissions/selected-actions":             `{"github_owned_allowed": true, "verified_allowed": false}`,
+		"/repos/org/site/actions/permissions/workflow":                     `{"default_workflow_permissions": "write", "can_approve_pull_request_reviews": false}`,
+		"/repos/org/site/actions/permissions/fork-pr-contributor-approval": `{"approval_policy": "first_time_contributors"}`,
+		"/repos/org/site/actions/runners":                                  `{"total_count": 1, "runners": [{"id": 1, "name": "build-1"}]}`,
+	}
+	suite.mux.HandleFunc("/repos/org/site/", func(w http.ResponseWriter, r *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		if response, ok := responses[r.URL.Path]; ok {
+			w.Write([]byte(response))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	repos, err := suite.newGithubService().GetRepositories("org", nil)
+
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), repos, 1)
+	actions := repos[0].actions
+	assert.True(suite.T(), *actions.Enabled)
+	assert.Equal(suite.T(), "selected", *actions.AllowedActions)
+	assert.True(suite.T(), actions.SelectedActions.GetGithubOwnedAllowed())
+	assert.Equal(suite.T(), "write", *actions.DefaultWorkflowPermissions)
+	assert.Equal(suite.T(), "first_time_contributors", *actions.ForkPrApprovalPolicy)
+	assert.Equal(suite.T(), []string{"build-1"}, actions.SelfHostedRunners)
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetOrganizationActionsSettings() {
+	suite.handleOrganization(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	suite.mux.HandleFunc("/orgs/org/actions/permissions", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`{"enabled_repositories": "all", "allowed_actions": "all"}`))
+	})
+	suite.mux.HandleFunc("/orgs/org/actions/permissions/workflow", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	suite.mux.HandleFunc("/orgs/org/actions/runner-groups", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`{"total_count": 2, "runner_groups": [
+			{"id": 1, "name": "Default", "allows_public_repositories": false},
+			{"id": 2, "name": "gpu", "allows_public_repositories": true}
+		]}`))
+	})
+
+	org, err := suite.newGithubService().GetOrganization("org")
+
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "all", *org.actions.AllowedActions)
+	assert.Nil(suite.T(), org.actions.SelectedActions)
+	assert.Nil(suite.T(), org.actions.DefaultWorkflowPermissions)
+	assert.Nil(suite.T(), org.actions.ForkPrApprovalPolicy)
+	assert.Equal(suite.T(), []string{"gpu"}, org.actions.PublicRunnerGroups)
+
+	// The settings that cannot be read are recorded, so the checks reading them are errored
+	assert.Contains(suite.T(), org.fetchErrs, "actions.default_workflow_permissions")
+	assert.Contains(suite.T(), org.fetchErrs, "actions.can_approve_pull_request_reviews")
+	assert.Contains(suite.T(), org.fetchErrs, "actions.fork_pr_approval_policy")
+	assert.NotContains(suite.T(), org.fetchErrs, "actions.allowed_actions")
+	assert.NotContains(suite.T(), org.fetchErrs, "actions.public_runner_groups")
+}
+
+// Serve an organization with the given custom repository roles response, two owners and SAML SSO enabled
+func (suite *RateLimitTransportTestSuite) handleOrganization(roles http.HandlerFunc) {
+	suite.mux.HandleFunc("/orgs/org", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`{"login": "org"}`))
+	})
+	suite.mux.HandleFunc("/orgs/org/custom-repository-roles", roles)
+	suite.mux.HandleFunc("/orgs/org/members", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(suite.T(), "admin", r.URL.Query().Get("role"))
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[{"login": "alice"}, {"login": "bob"}]`))
+	})
+	suite.mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(suite.T(), http.MethodPost, r.Method)
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`{"data": {"organization": {"samlIdentityProvider": {"id": "MDI"}}}}`))
+	})
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetOrganization() {
+	suite.handleOrganization(func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`{"total_count": 1, "custom_roles": [{"name": "Security Engineer", "base_role": "maintain", "permissions": ["write_code_scanning", "delete_alerts_code_scanning"]}]}`))
+	})
+
+	org, err := suite.newGithubService().GetOrganization("org")
+
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), org.customRepositoryRoles, 1)
+	assert.Equal(suite.T(), "maintain", org.customRepositoryRoles[0].GetBaseRole())
+	assert.Equal(suite.T(), []string{"alice", "bob"}, org.owners)
+	require.NotNil(suite.T(), org.samlSsoEnabled)
+	assert.True(suite.T(), *org.samlSsoEnabled)
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetOrganizationWithoutCustomRepositoryRoles() {
+	suite.handleOrganization(func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+	})
+
+	org, err := suite.newGithubService().GetOrganization("org")
+
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), org.customRepositoryRoles)
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetOrganizationForbiddenCustomRepositoryRoles() {
+	suite.handleOrganization(func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		http.Error(w, `{"message": "Resource not accessible by integration"}`, http.StatusForbidden)
+	})
+
+	org, err := suite.newGithubService().GetOrganization("org")
+
+	require.NoError(suite.T(), err)
+	assert.Nil(suite.T(), org.customRepositoryRoles)
+	assert.ErrorContains(suite.T(), org.fetchErrs["custom_repository_roles"], "Resource not accessible by integration")
+	assert.Equal(suite.T(), []string{"alice", "bob"}, org.owners)
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetOrganizationRecordsPermissionErrors() {
+	suite.mux.HandleFunc("/orgs/org", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`{"login": "org"}`))
+	})
+	suite.mux.HandleFunc("/orgs/org/members", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		http.Error(w, `{"message": "Resource not accessible by integration"}`, http.StatusForbidden)
+	})
+	suite.mux.HandleFunc("/graphql", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`{"data": {"organization": null}, "errors": [{"message": "Resource not accessible by integration"}]}`))
+	})
+
+	org, err := suite.newGithubService().GetOrganization("org")
+
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "org", org.GetLogin())
+	assert.Nil(suite.T(), org.owners)
+	assert.Nil(suite.T(), org.samlSsoEnabled)
+	assert.ErrorContains(suite.T(), org.fetchErrs["owners"], "unable to list the owners of org")
+	assert.EqualError(suite.T(), org.fetchErrs["saml_sso_enabled"], "unable to read the SAML settings of org: Resource not accessible by integration")
+	for _, field := range []string{"two_factor_requirement_enabled", "default_repository_permission", "web_commit_signoff_required"} {
+		assert.EqualError(suite.T(), org.fetchErrs[field], "only returned to the organization owners")
+	}
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetRepositoriesRecordsOutsideCollaboratorErrors() {
+	suite.mux.HandleFunc("/orgs/org/repos", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[{"name": "app", "default_branch": "main"}]`))
+	})
+	suite.mux.HandleFunc("/repos/org/", func(w http.ResponseWriter, r *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		if r.URL.Path == "/repos/org/app/collaborators" {
+			http.Error(w, `{"message": "Must have push access to view repository collaborators."}`, http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+
+	repos, err := suite.newGithubService().GetRepositories("org", nil)
+
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), repos, 1)
+	assert.Nil(suite.T(), repos[0].outsideAdminCollaborators)
+	assert.ErrorContains(suite.T(), repos[0].fetchErrs["outside_admin_collaborators"], "Must have push access")
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetTeams() {
+	suite.mux.HandleFunc("/orgs/org/teams", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[{"slug": "web", "privacy": "closed"}, {"slug": "ops", "privacy": "secret"}]`))
+	})
+	maintainers := map[string]string{
+		"web": `[{"login": "alice"}, {"login": "bob"}]`,
+		"ops": `[{"login": "alice"}]`,
+	}
+	repos := map[string]string{
+		"web": `[{"name": "site", "permissions": {"admin": false, "push": true}}]`,
+		"ops": `[{"name": "infra", "permissions": {"admin": true}}, {"name": "site", "permissions": {"admin": false}}]`,
+	}
+	suite.mux.HandleFunc("/orgs/org/teams/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.URL.Path, "/")
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		switch parts[5] {
+		case "members":
+			assert.Equal(suite.T(), "maintainer", r.URL.Query().Get("role"))
+			w.Write([]byte(maintainers[parts[4]]))
+		case "repos":
+			w.Write([]byte(repos[parts[4]]))
+		}
+	})
+
+	teams, err := suite.newGithubService().GetTeams("org")
+
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), teams, 2)
+	assert.Equal(suite.T(), "web", teams[0].GetSlug())
+	assert.Equal(suite.T(), []teamMaintainer{{Login: "alice", MaintainedTeams: 2}, {Login: "bob", MaintainedTeams: 1}}, teams[0].maintainers)
+	assert.Equal(suite.T(), []string{}, teams[0].adminRepositories)
+	assert.Equal(suite.T(), []string{"infra"}, teams[1].adminRepositories)
+	assert.Empty(suite.T(), teams[0].fetchErrs)
+}
+
+func (suite *RateLimitTransportTestSuite) TestGetTeamsKeepsTheTeamsFetched() {
+	suite.mux.HandleFunc("/orgs/org/teams", func(w http.ResponseWriter, _ *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		w.Write([]byte(`[{"slug": "web"}, {"slug": "ops"}]`))
+	})
+	suite.mux.HandleFunc("/orgs/org/teams/", func(w http.ResponseWriter, r *http.Request) {
+		rateHeaders(w, 4000, time.Now().Add(time.Hour))
+		if r.URL.Path == "/orgs/org/teams/ops/members" {
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`[{"login": "alice"}]`))
+	})
+
+	teams, err := suite.newGithubService().GetTeams("org")
+
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), teams, 2)
+	assert.Equal(suite.T(), []teamMaintainer{{Login: "alice", MaintainedTeams: 1}}, teams[0].maintainers)
+	assert.Equal(suite.T(), []string{}, teams[0].adminRepositories)
+	assert.EqualError(suite.T(), teams[0].fetchErrs["maintainers.maintained_teams"], "the maintainers of ops could not be fetched")
+	assert.NotContains(suite.T(), teams[0].fetchErrs, "maintainers")
+	assert.Nil(suite.T(), teams[1].maintainers)
+	assert.ErrorContains(suite.T(), teams[1].fetchErrs["maintainers"], "unable to list the maintainers of team ops")
+}
+
+func (suite *RateLimitTransportTestSuite) newGithubService() *GithubService {
+	client := github.NewClient(suite.client)
+	client.BaseURL, _ = url.Parse(suite.server.URL + "/")
+	return &GithubService{
+		client:    client,
+		transport: suite.transport,
+	}
+}
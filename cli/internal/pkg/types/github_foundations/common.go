@@ -15,3 +15,15 @@ func toCtyValueSlice(values []string) cty.Value {
 	}
 	return cty.ListVal(ctyValues)
 }
+
+func toCtyValueMap(values map[string]string) cty.Value {
+	if len(values) == 0 {
+		return cty.MapValEmpty(cty.String)
+	}
+
+	ctyValues := make(map[string]cty.Value, len(values))
+	for k, v := range values {
+		ctyValues[k] = cty.StringVal(v)
+	}
+	return cty.MapVal(ctyValues)
+}
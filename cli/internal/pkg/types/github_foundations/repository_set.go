@@ -1,6 +1,8 @@
 package githubfoundations
 
 import (
+	"slices"
+
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
 )
@@ -29,6 +31,9 @@ func (r *RepositorySetInput) WriteHCL(file *hclwrite.File) {
 
 	rootBodyMap["private_repositories"] = cty.ObjectVal(privateRepositories)
 	rootBodyMap["public_repositories"] = cty.ObjectVal(publicRepositories)
+	if len(r.DefaultRepositoryTeamPermissions) > 0 {
+		rootBodyMap["default_repository_team_permissions"] = toCtyValueMap(r.DefaultRepositoryTeamPermissions)
+	}
 	rootBody.SetAttributeValue("inputs", cty.ObjectVal(rootBodyMap))
 }
 
@@ -50,6 +55,7 @@ type RepositoryInput struct {
 	DependabotSecurityUpdates         bool
 	AllowAutoMerge                    bool
 	// Optional
+	AllowUpdateBranch             bool
 	OrganizationActionSecrets     []string
 	OrganizationCodespaceSecrets  []string
 	OrganizationDependabotSecrets []string
@@ -60,6 +66,15 @@ type RepositoryInput struct {
 	TemplateRepository            *TemplateRepositoryInputs
 	LicenseTemplate               string
 	UserPermissions               map[string]string
+	// The inputs given in the repository set the repository was read from, nil when it
+	// was not read from a repository set
+	SetInputs []string
+}
+
+// IsSet reports whether the repository set gives the input. Every input is considered
+// set when the repository was not read from a repository set.
+func (r *RepositoryInput) IsSet(input string) bool {
+	return r.SetInputs == nil || slices.Contains(r.SetInputs, input)
 }
 
 func (r *RepositoryInput) GetCtyValue() cty.Value {
@@ -73,12 +88,25 @@ func (r *RepositoryInput) GetCtyValue() cty.Value {
 	mapVal["topics"] = toCtyValueSlice(r.Topics)
 	mapVal["homepage"] = cty.StringVal(r.Homepage)
 	mapVal["delete_head_on_merge"] = cty.BoolVal(r.DeleteHeadBranchOnMerge)
-	mapVal["requires_web_commit_signing"] = cty.BoolVal(r.RequiresWebCommitSignOff)
 	mapVal["dependabot_security_updates"] = cty.BoolVal(r.DependabotSecurityUpdates)
-	mapVal["protected_branches"] = toCtyValueSlice(r.ProtectedBranches)
 	mapVal["allow_auto_merge"] = cty.BoolVal(r.AllowAutoMerge)
+	mapVal["repository_team_permissions_override"] = toCtyValueMap(r.RepositoryTeamPermissionsOverride)
 
 	// Optional fields
+	// An empty protected_branches list disables branch protection, so the input is only
+	// written when the branches are known
+	if r.ProtectedBranches != nil {
+		mapVal["protected_branches"] = toCtyValueSlice(r.ProtectedBranches)
+	}
+
+	if r.IsSet("requires_web_commit_signing") {
+		mapVal["requires_web_commit_signing"] = cty.BoolVal(r.RequiresWebCommitSignOff)
+	}
+
+	if r.AllowUpdateBranch {
+		mapVal["allow_update_branch"] = cty.True
+	}
+
 	if len(r.OrganizationActionSecrets) > 0 {
 		mapVal["organization_action_secrets"] = toCtyValueSlice(r.OrganizationActionSecrets)
 	}
@@ -92,35 +120,19 @@ func (r *RepositoryInput) GetCtyValue() cty.Value {
 	}
 
 	if len(r.ActionSecrets) > 0 {
-		actionSecretsMap := make(map[string]cty.Value)
-		for key, val := range r.ActionSecrets {
-			actionSecretsMap[key] = cty.StringVal(val)
-		}
-		mapVal["action_secrets"] = cty.MapVal(actionSecretsMap)
+		mapVal["action_secrets"] = toCtyValueMap(r.ActionSecrets)
 	}
 	if len(r.CodespaceSecrets) > 0 {
-		codespaceSecretsMap := make(map[string]cty.Value)
-		for key, val := range r.CodespaceSecrets {
-			codespaceSecretsMap[key] = cty.StringVal(val)
-		}
-		mapVal["codespace_secrets"] = cty.MapVal(codespaceSecretsMap)
+		mapVal["codespace_secrets"] = toCtyValueMap(r.CodespaceSecrets)
 	}
 	if len(r.DependabotSecrets) > 0 {
-		dependabotSecretsMap := make(map[string]cty.Value)
-		for key, val := range r.DependabotSecrets {
-			dependabotSecretsMap[key] = cty.StringVal(val)
-		}
-		mapVal["dependabot_secrets"] = cty.MapVal(dependabotSecretsMap)
+		mapVal["dependabot_secrets"] = toCtyValueMap(r.DependabotSecrets)
 	}
 	if len(r.Environments) > 0 {
 		environmentsMap := make(map[string]cty.Value)
 		for key, val := range r.Environments {
 			environmentMap := make(map[string]cty.Value)
-			actionSecretsMap := make(map[string]cty.Value)
-			for key, val := range val.ActionSecrets {
-				actionSecretsMap[key] = cty.StringVal(val)
-			}
-			environmentMap["action_secrets"] = cty.MapVal(actionSecretsMap)
+			environmentMap["action_secrets"] = toCtyValueMap(val.ActionSecrets)
 			environmentsMap[key] = cty.ObjectVal(environmentMap)
 		}
 		mapVal["environments"] = cty.MapVal(environmentsMap)
@@ -139,11 +151,7 @@ func (r *RepositoryInput) GetCtyValue() cty.Value {
 	}
 
 	if len(r.UserPermissions) > 0 {
-		userPermissionsMap := make(map[string]cty.Value)
-		for key, val := range r.UserPermissions {
-			userPermissionsMap[key] = cty.StringVal(val)
-		}
-		mapVal["user_permissions"] = cty.MapVal(userPermissionsMap)
+		mapVal["user_permissions"] = toCtyValueMap(r.UserPermissions)
 	}
 	return cty.ObjectVal(mapVal)
 }
@@ -1,6 +1,8 @@
 package githubfoundations
 
 import (
+	"strings"
+
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
 )
@@ -47,3 +49,16 @@ func (t *TeamInput) GetCtyValue() cty.Value {
 
 	return cty.ObjectVal(mapVal)
 }
+
+// HasMember tells whether the user is a maintainer or a member of the team, GitHub logins
+// being compared without case
+func (t *TeamInput) HasMember(user string) bool {
+	for _, users := range [][]string{t.Maintainers, t.Members} {
+		for _, u := range users {
+			if strings.EqualFold(u, user) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package types
+
+import "fmt"
+
+const repositoryURL = "https://github.com/canada-ca/fondations-github-foundations"
+
+// Guardrail identifies one of the GoC SCM guardrails documented in guardrails/EN
+type Guardrail string
+
+const (
+	ProtectUserAccounts          Guardrail = "01"
+	ManageAccess                 Guardrail = "02"
+	SecureEndpoints              Guardrail = "03"
+	EnterpriseMonitoringAccounts Guardrail = "04"
+	DataProtection               Guardrail = "05"
+	NetworkSecurityServices      Guardrail = "06"
+	CyberDefenseServices         Guardrail = "07"
+	LoggingAndMonitoring         Guardrail = "08"
+	PlanForContinuity            Guardrail = "09"
+)
+
+type guardrailDocument struct {
+	title    string
+	fileName string
+}
+
+var guardrailDocuments = map[Guardrail]guardrailDocument{
+	ProtectUserAccounts:          {"Protect User Accounts and Identities", "01_Protect-user-accounts-and-identities.md"},
+	ManageAccess:                 {"Manage Access", "02_Manage-Role-Access.md"},
+	SecureEndpoints:              {"Secure Endpoints", "03_Secure-Endpoints.md"},
+	EnterpriseMonitoringAccounts: {"Enterprise Monitoring Accounts", "04_Enterprise-Monitoring-Accounts.md"},
+	DataProtection:               {"Data Protection", "05_Data-Protection.md"},
+	NetworkSecurityServices:      {"Network Security Services", "06_Network-Security-Services.md"},
+	CyberDefenseServices:         {"Cyber Defense Services", "07_Cyber-Defense-Services.md"},
+	LoggingAndMonitoring:         {"Logging and Monitoring", "08_Logging-and-Monitoring.md"},
+	PlanForContinuity:            {"Plan for Continuity", "09_Plan-for-Continuity.md"},
+}
+
+// Whether the guardrail is one of the documented guardrails
+func (g Guardrail) Valid() bool {
+	_, ok := guardrailDocuments[g]
+	return ok
+}
+
+func (g Guardrail) Title() string {
+	if doc, ok := guardrailDocuments[g]; ok {
+		return doc.title
+	}
+	return string(g)
+}
+
+// The path of the guardrail's documentation, relative to the root of the repository
+func (g Guardrail) DocumentPath() string {
+	if doc, ok := guardrailDocuments[g]; ok {
+		return fmt.Sprintf("guardrails/EN/%s", doc.fileName)
+	}
+	return "GUARDRAILS.md"
+}
+
+// The URL of the guardrail's documentation on GitHub
+func (g Guardrail) DocumentURL() string {
+	return fmt.Sprintf("%s/blob/main/%s", repositoryURL, g.DocumentPath())
+}
+
+func (g Guardrail) String() string {
+	return fmt.Sprintf("%s %s", string(g), g.Title())
+}
@@ -0,0 +1,113 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gh_foundations/internal/pkg/types"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultPath is the history file check runs are appended to when --history is given
+// without a path, and the history read by default
+const DefaultPath = "check_history.jsonl"
+
+// Run holds the reports of one check run. Runs are stored as JSON lines, one run per
+// line, so new runs can be appended without reading the history.
+type Run struct {
+	// RFC 3339 timestamp of the run, the key of the run in the history
+	Timestamp    string              `json:"rfc3339_timestamp"`
+	Organization string              `json:"organization"`
+	Reports      []types.CheckReport `json:"reports"`
+	// The guardrails of the checks run, by entity type and check id, as mapped by the
+	// policy of the run. Missing from the runs recorded before the mapping was stored.
+	Guardrails map[string][]types.Guardrail `json:"guardrails,omitempty"`
+}
+
+// NewRun records the reports of a run along with the guardrails of the checks they ran,
+// so the trend keeps them when the policy changes
+func NewRun(timestamp string, organization string, reports []types.CheckReport, definitions []types.CheckDefinition) Run {
+	run := Run{Timestamp: timestamp, Organization: organization, Reports: reports, Guardrails: make(map[string][]types.Guardrail)}
+	byKey := make(map[string][]types.Guardrail)
+	for _, def := range definitions {
+		byKey[guardrailKey(def.EntityType, def.Id)] = def.Guardrails
+	}
+	for _, report := range reports {
+		for checkId := range report.Checks {
+			key := guardrailKey(report.EntityType, checkId)
+			if guardrails, ok := byKey[key]; ok {
+				run.Guardrails[key] = guardrails
+			}
+		}
+	}
+	return run
+}
+
+func guardrailKey(entityType string, checkId string) string {
+	return entityType + "/" + checkId
+}
+
+// Append adds a run at the end of the history file, creating it when needed
+func Append(path string, run Run) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open the check history: %w", err)
+	}
+	defer file.Close()
+
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("unable to write to the check history: %w", err)
+	}
+	return nil
+}
+
+// Load reads the runs of an organization from the history file, oldest first. Every
+// organization's runs are returned when organization is empty.
+func Load(path string, organization string) ([]Run, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the check history: %w", err)
+	}
+	defer file.Close()
+
+	var runs []Run
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var run Run
+			if err := json.Unmarshal(line, &run); err != nil {
+				return nil, fmt.Errorf("invalid check history %s line %d: %w", path, lineNumber, err)
+			}
+			if organization == "" || strings.EqualFold(run.Organization, organization) {
+				runs = append(runs, run)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the check history: %w", err)
+		}
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Time().Before(runs[j].Time())
+	})
+	return runs, nil
+}
+
+// Time parses the timestamp of the run, the zero time when it is invalid
+func (r Run) Time() time.Time {
+	t, _ := time.Parse(time.RFC3339, r.Timestamp)
+	return t
+}
@@ -0,0 +1,45 @@
+package history
+
+import (
+	"gh_foundations/internal/pkg/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultPath)
+
+	require.NoError(t, Append(path, Run{Timestamp: "2026-10-02T12:00:00Z", Organization: "octo-org", Reports: []types.CheckReport{{EntityId: "octo-org", Checks: map[string]types.CheckResult{"saml_sso_enabled": types.Passed}}}}))
+	require.NoError(t, Append(path, Run{Timestamp: "2026-10-01T12:00:00Z", Organization: "other-org"}))
+	// Runs recorded in another time zone are ordered by time
+	require.NoError(t, Append(path, Run{Timestamp: "2026-10-02T09:00:00-05:00", Organization: "Octo-Org"}))
+
+	runs, err := Load(path, "octo-org")
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "2026-10-02T12:00:00Z", runs[0].Timestamp)
+	assert.Equal(t, "octo-org", runs[0].Reports[0].EntityId)
+	assert.Equal(t, types.CheckResult(types.Passed), runs[0].Reports[0].Checks["saml_sso_enabled"])
+	assert.Equal(t, "2026-10-02T09:00:00-05:00", runs[1].Timestamp)
+
+	runs, err = Load(path, "")
+	require.NoError(t, err)
+	assert.Len(t, runs, 3)
+	assert.Equal(t, "other-org", runs[0].Organization)
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := Load(filepath.Join(dir, "missing.jsonl"), "")
+	assert.ErrorContains(t, err, "unable to open the check history")
+
+	path := filepath.Join(dir, DefaultPath)
+	require.NoError(t, os.WriteFile(path, []byte("{\"organization\": \"octo-org\"}\n\nnot json\n"), 0644))
+	_, err = Load(path, "")
+	assert.ErrorContains(t, err, "line 3")
+}
@@ -0,0 +1,147 @@
+package history
+
+import (
+	"gh_foundations/internal/pkg/types"
+	"sort"
+)
+
+type Change string
+
+const (
+	Appeared Change = "appeared"
+	Fixed    Change = "fixed"
+)
+
+// Event records a violation appearing or getting fixed between two runs
+type Event struct {
+	Timestamp  string            `json:"rfc3339_timestamp"`
+	Change     Change            `json:"change"`
+	EntityType string            `json:"entity_type"`
+	EntityId   string            `json:"entity_id"`
+	CheckId    string            `json:"check_id"`
+	Guardrails []types.Guardrail `json:"guardrails"`
+	// The violation, or the last violation reported before the fix
+	Message string `json:"message"`
+}
+
+// RunSummary counts the check results of a run, to show the trend over time
+type RunSummary struct {
+	Timestamp  string `json:"rfc3339_timestamp"`
+	Passed     int    `json:"passed"`
+	Failed     int    `json:"failed"`
+	Violations int    `json:"violations"`
+}
+
+// Trend is the evolution of the check results over the runs of the history
+type Trend struct {
+	Runs   []RunSummary `json:"runs"`
+	Events []Event      `json:"events"`
+}
+
+type checkKey struct {
+	entityType string
+	entityId   string
+	checkId    string
+}
+
+// NewTrend compares every run with the runs before it. A violation appears in the first
+// run reporting it, and is fixed in the first run where its check passes again. Checks
+// that are not run, e.g. because the run was limited to some repositories, leave the
+// violation as it was. Events map to the guardrails stored with their run, the
+// definitions only being used for the runs recorded without them.
+func NewTrend(runs []Run, definitions []types.CheckDefinition) Trend {
+	current := make(map[string][]types.Guardrail)
+	for _, def := range definitions {
+		current[guardrailKey(def.EntityType, def.Id)] = def.Guardrails
+	}
+
+	trend := Trend{Runs: []RunSummary{}, Events: []Event{}}
+	// The open violations and their messages
+	open := make(map[checkKey]string)
+
+	for _, run := range runs {
+		guardrails := run.Guardrails
+		if guardrails == nil {
+			guardrails = current
+		}
+		summary := RunSummary{Timestamp: run.Timestamp}
+		for _, report := range run.Reports {
+			violations := make(map[string]string)
+			for _, checkErr := range report.Errors {
+				for checkId, message := range checkErr.Violations {
+					violations[checkId] = message
+				}
+			}
+
+			for _, checkId := range sortedChecks(report.Checks) {
+				key := checkKey{report.EntityType, report.EntityId, checkId}
+				event := Event{
+					Timestamp:  run.Timestamp,
+					EntityType: report.EntityType,
+					EntityId:   report.EntityId,
+					CheckId:    checkId,
+					Guardrails: guardrails[guardrailKey(report.EntityType, checkId)],
+				}
+
+				switch report.Checks[checkId] {
+				case types.Passed:
+					summary.Passed++
+					if message, ok := open[key]; ok {
+						delete(open, key)
+						event.Change, event.Message = Fixed, message
+						trend.Events = append(trend.Events, event)
+					}
+				case types.Failed:
+					summary.Failed++
+					summary.Violations++
+					if _, ok := open[key]; !ok {
+						event.Change, event.Message = Appeared, violations[checkId]
+						trend.Events = append(trend.Events, event)
+					}
+					open[key] = violations[checkId]
+				}
+			}
+		}
+		trend.Runs = append(trend.Runs, summary)
+	}
+	return trend
+}
+
+// GuardrailEvents lists the events of the checks that map to the guardrail
+func (t Trend) GuardrailEvents(guardrail types.Guardrail) []Event {
+	var events []Event
+	for _, e := range t.Events {
+		for _, g := range e.Guardrails {
+			if g == guardrail {
+				events = append(events, e)
+				break
+			}
+		}
+	}
+	return events
+}
+
+// Guardrails lists the guardrails with events, in order
+func (t Trend) Guardrails() []types.Guardrail {
+	seen := make(map[types.Guardrail]bool)
+	var guardrails []types.Guardrail
+	for _, e := range t.Events {
+		for _, g := range e.Guardrails {
+			if !seen[g] {
+				seen[g] = true
+				guardrails = append(guardrails, g)
+			}
+		}
+	}
+	sort.Slice(guardrails, func(i, j int) bool { return guardrails[i] < guardrails[j] })
+	return guardrails
+}
+
+func sortedChecks(checks map[string]types.CheckResult) []string {
+	ids := make([]string, 0, len(checks))
+	for id := range checks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
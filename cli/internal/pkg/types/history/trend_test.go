@@ -0,0 +1,89 @@
+package history
+
+import (
+	"gh_foundations/internal/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDefinitions = []types.CheckDefinition{
+	{Id: "secret_scanning", EntityType: "github_repository", Guardrails: []types.Guardrail{types.DataProtection, types.CyberDefenseServices}},
+	{Id: "delete_branch_on_merge", EntityType: "github_repository", Guardrails: []types.Guardrail{types.CyberDefenseServices}},
+}
+
+func repositoryReport(id string, violations map[string]string, passed ...string) types.CheckReport {
+	report := types.CheckReport{EntityType: "github_repository", EntityId: id, Checks: map[string]types.CheckResult{}}
+	for _, checkId := range passed {
+		report.Checks[checkId] = types.Passed
+	}
+	for checkId := range violations {
+		report.Checks[checkId] = types.Failed
+	}
+	if len(violations) > 0 {
+		report.Errors = []types.CheckError{{Check: types.GoCGuardrails, Violations: violations}}
+	}
+	return report
+}
+
+func TestNewTrend(t *testing.T) {
+	runs := []Run{
+		{Timestamp: "2026-10-01T00:00:00Z", Reports: []types.CheckReport{
+			repositoryReport("app", map[string]string{"secret_scanning": "disabled"}, "delete_branch_on_merge"),
+		}},
+		// A run limited to another repository leaves the violations of app open
+		{Timestamp: "2026-10-02T00:00:00Z", Reports: []types.CheckReport{
+			repositoryReport("site", nil, "secret_scanning"),
+		}},
+		{Timestamp: "2026-10-03T00:00:00Z", Reports: []types.CheckReport{
+			repositoryReport("app", map[string]string{"delete_branch_on_merge": "not enabled"}, "secret_scanning"),
+		}},
+	}
+
+	trend := NewTrend(runs, testDefinitions)
+
+	assert.Equal(t, []RunSummary{
+		{Timestamp: "2026-10-01T00:00:00Z", Passed: 1, Failed: 1, Violations: 1},
+		{Timestamp: "2026-10-02T00:00:00Z", Passed: 1},
+		{Timestamp: "2026-10-03T00:00:00Z", Passed: 1, Failed: 1, Violations: 1},
+	}, trend.Runs)
+
+	require.Len(t, trend.Events, 3)
+	assert.Equal(t, Event{
+		Timestamp: "2026-10-01T00:00:00Z", Change: Appeared, EntityType: "github_repository", EntityId: "app",
+		CheckId: "secret_scanning", Guardrails: testDefinitions[0].Guardrails, Message: "disabled",
+	}, trend.Events[0])
+	assert.Equal(t, Appeared, trend.Events[1].Change)
+	assert.Equal(t, "delete_branch_on_merge", trend.Events[1].CheckId)
+	assert.Equal(t, "2026-10-03T00:00:00Z", trend.Events[1].Timestamp)
+	assert.Equal(t, Fixed, trend.Events[2].Change)
+	assert.Equal(t, "secret_scanning", trend.Events[2].CheckId)
+	assert.Equal(t, "disabled", trend.Events[2].Message)
+
+	assert.Equal(t, []types.Guardrail{types.DataProtection, types.CyberDefenseServices}, trend.Guardrails())
+	assert.Len(t, trend.GuardrailEvents(types.DataProtection), 2)
+	assert.Len(t, trend.GuardrailEvents(types.CyberDefenseServices), 3)
+}
+
+func TestNewTrendKeepsTheGuardrailsOfTheRun(t *testing.T) {
+	// The run was recorded when secret_scanning only mapped to data protection
+	run := NewRun("2026-10-01T00:00:00Z", "octo-org", []types.CheckReport{
+		repositoryReport("app", map[string]string{"secret_scanning": "disabled"}),
+	}, []types.CheckDefinition{
+		{Id: "secret_scanning", EntityType: "github_repository", Guardrails: []types.Guardrail{types.DataProtection}},
+		{Id: "unused", EntityType: "github_repository", Guardrails: []types.Guardrail{types.DataProtection}},
+	})
+	assert.Equal(t, map[string][]types.Guardrail{"github_repository/secret_scanning": {types.DataProtection}}, run.Guardrails)
+
+	// Runs recorded without the mapping use the current policy
+	legacy := Run{Timestamp: "2026-10-02T00:00:00Z", Reports: []types.CheckReport{
+		repositoryReport("site", map[string]string{"secret_scanning": "disabled"}),
+	}}
+
+	trend := NewTrend([]Run{run, legacy}, testDefinitions)
+
+	require.Len(t, trend.Events, 2)
+	assert.Equal(t, []types.Guardrail{types.DataProtection}, trend.Events[0].Guardrails)
+	assert.Equal(t, testDefinitions[0].Guardrails, trend.Events[1].Guardrails)
+}
@@ -0,0 +1,347 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+type operator struct {
+	// Describes what was expected, completing "Expected it to ..."
+	describe func(c *Condition) string
+	// Whether the operator applies nested conditions to array elements
+	nested bool
+	test   func(c *Condition, exists bool, value any, raw gjson.Result) bool
+	// Lists the elements that made a nested operator fail, to point them out in the violation
+	offending func(c *Condition, raw gjson.Result) []gjson.Result
+	// Lists the requirements the value does not meet, replacing the actual value in the violation
+	unmet func(c *Condition, value any) []string
+}
+
+var operators map[string]operator
+
+// The operators are set up in init as the nested operators evaluate conditions recursively
+func init() {
+	operators = map[string]operator{
+		"equals": {
+			describe: func(c *Condition) string { return fmt.Sprintf("be %s", format(c.Expected)) },
+			test: func(c *Condition, exists bool, value any, _ gjson.Result) bool {
+				return exists && reflect.DeepEqual(value, normalize(c.Expected))
+			},
+		},
+		"not_equals": {
+			describe: func(c *Condition) string { return fmt.Sprintf("not be %s", format(c.Expected)) },
+			test: func(c *Condition, exists bool, value any, _ gjson.Result) bool {
+				return !exists || !reflect.DeepEqual(value, normalize(c.Expected))
+			},
+		},
+		"in": {
+			describe: func(c *Condition) string { return fmt.Sprintf("be one of %s", format(c.Expected)) },
+			test: func(c *Condition, exists bool, value any, _ gjson.Result) bool {
+				return exists && containsValue(normalize(c.Expected), value)
+			},
+		},
+		"not_in": {
+			describe: func(c *Condition) string { return fmt.Sprintf("not be one of %s", format(c.Expected)) },
+			test: func(c *Condition, exists bool, value any, _ gjson.Result) bool {
+				return !exists || !containsValue(normalize(c.Expected), value)
+			},
+		},
+		"min": {
+			describe: func(c *Condition) string { return fmt.Sprintf("be at least %s", format(c.Expected)) },
+			test: func(c *Condition, exists bool, value any, _ gjson.Result) bool {
+				actual, ok := value.(float64)
+				expected, expectedOk := normalize(c.Expected).(float64)
+				return exists && ok && expectedOk && actual >= expected
+			},
+		},
+		"max": {
+			describe: func(c *Condition) string { return fmt.Sprintf("be at most %s", format(c.Expected)) },
+			test: func(c *Condition, exists bool, value any, _ gjson.Result) bool {
+				actual, ok := value.(float64)
+				expected, expectedOk := normalize(c.Expected).(float64)
+				return exists && ok && expectedOk && actual <= expected
+			},
+		},
+		"at_least": {
+			describe: func(c *Condition) string { return fmt.Sprintf("meet at least %s", format(c.Expected)) },
+			test: func(c *Condition, exists bool, value any, _ gjson.Result) bool {
+				return exists && len(unmetRequirements("", normalize(c.Expected), value)) == 0
+			},
+			unmet: func(c *Condition, value any) []string {
+				return unmetRequirements("", normalize(c.Expected), value)
+			},
+		},
+		"contains_all": {
+			describe: func(c *Condition) string { return fmt.Sprintf("contain all of %s", format(c.Expected)) },
+			test: func(c *Condition, exists bool, value any, _ gjson.Result) bool {
+				present := valueSet(value)
+				for _, item := range expectedItems(c.Expected) {
+					if !present[key(item)] {
+						return false
+					}
+				}
+				return exists
+			},
+		},
+		"contains_none": {
+			describe: func(c *Condition) string { return fmt.Sprintf("contain none of %s", format(c.Expected)) },
+			test: func(c *Condition, _ bool, value any, _ gjson.Result) bool {
+				present := valueSet(value)
+				for _, item := range expectedItems(c.Expected) {
+					if present[key(item)] {
+						return false
+					}
+				}
+				return true
+			},
+		},
+		"exists": {
+			describe: func(c *Condition) string { return "be set" },
+			test: func(_ *Condition, exists bool, value any, _ gjson.Result) bool {
+				return exists && value != nil
+			},
+		},
+		"not_exists": {
+			describe: func(c *Condition) string { return "not be set" },
+			test: func(_ *Condition, exists bool, value any, _ gjson.Result) bool {
+				return !exists || value == nil
+			},
+		},
+		"matches": {
+			describe: func(c *Condition) string { return fmt.Sprintf("match %s", format(c.Expected)) },
+			test: func(c *Condition, exists bool, value any, _ gjson.Result) bool {
+				actual, ok := value.(string)
+				return exists && ok && regexp.MustCompile(c.Expected.(string)).MatchString(actual)
+			},
+		},
+		"any": {
+			nested:   true,
+			describe: func(c *Condition) string { return "have an element matching every nested condition" },
+			test: func(c *Condition, _ bool, _ any, raw gjson.Result) bool {
+				for _, element := range raw.Array() {
+					if c.matchesElement(element) {
+						return true
+					}
+				}
+				return false
+			},
+		},
+		"all": {
+			nested:   true,
+			describe: func(c *Condition) string { return "only have elements matching every nested condition" },
+			test: func(c *Condition, _ bool, _ any, raw gjson.Result) bool {
+				for _, element := range raw.Array() {
+					if !c.matchesElement(element) {
+						return false
+					}
+				}
+				return true
+			},
+			offending: func(c *Condition, raw gjson.Result) []gjson.Result {
+				return c.filterElements(raw, false)
+			},
+		},
+		"none": {
+			nested:   true,
+			describe: func(c *Condition) string { return "have no element matching every nested condition" },
+			test: func(c *Condition, _ bool, _ any, raw gjson.Result) bool {
+				for _, element := range raw.Array() {
+					if c.matchesElement(element) {
+						return false
+					}
+				}
+				return true
+			},
+			offending: func(c *Condition, raw gjson.Result) []gjson.Result {
+				return c.filterElements(raw, true)
+			},
+		},
+	}
+}
+
+func (o operator) evaluate(c *Condition, exists bool, value any, raw gjson.Result) error {
+	if o.test(c, exists, value, raw) {
+		return nil
+	}
+	actual := "not set"
+	if exists && !o.nested {
+		actual = format(value)
+	} else if exists {
+		actual = fmt.Sprintf("a list of %d elements", len(raw.Array()))
+	}
+	message := fmt.Sprintf("%s is %s. Expected it to %s", c.Field, actual, o.describe(c))
+	if exists && o.unmet != nil {
+		message = fmt.Sprintf("%s does not meet the requirements. Expected %s", c.Field, strings.Join(o.unmet(c, value), ", "))
+	}
+	if o.offending != nil {
+		var found []string
+		for _, element := range o.offending(c, raw) {
+			found = append(found, format(element.Value()))
+		}
+		message += fmt.Sprintf(", found %s", strings.Join(found, ", "))
+	}
+	return errors.New(message)
+}
+
+// The elements of an array that do, or do not, match every nested condition
+func (c *Condition) filterElements(raw gjson.Result, matching bool) []gjson.Result {
+	var elements []gjson.Result
+	for _, element := range raw.Array() {
+		if c.matchesElement(element) == matching {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+func (c *Condition) matchesElement(element gjson.Result) bool {
+	var allErrors error
+	for _, nested := range c.Conditions {
+		allErrors = errors.Join(allErrors, nested.evaluate(element))
+	}
+	return allErrors == nil
+}
+
+// Compare a value to the minimum requirements of at_least. Numbers must be at least the
+// expected number, true must be true while false requires nothing, lists must contain
+// every expected element and objects are compared field by field. Other values must be
+// equal. Returns a description of every requirement that is not met.
+func unmetRequirements(path string, expected any, actual any) []string {
+	describe := func(requirement string) []string {
+		name := path
+		if name == "" {
+			name = "it"
+		}
+		found := "not set"
+		if actual != nil {
+			found = format(actual)
+		}
+		return []string{fmt.Sprintf("%s to %s (found %s)", name, requirement, found)}
+	}
+
+	switch e := expected.(type) {
+	case map[string]any:
+		fields, ok := actual.(map[string]any)
+		if !ok {
+			if len(e) == 0 || actual == nil {
+				return describe("be set")
+			}
+			return describe(fmt.Sprintf("be an object meeting %s", format(e)))
+		}
+		keys := make([]string, 0, len(e))
+		for k := range e {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		var unmet []string
+		for _, k := range keys {
+			name := k
+			if path != "" {
+				name = path + "." + k
+			}
+			unmet = append(unmet, unmetRequirements(name, e[k], fields[k])...)
+		}
+		return unmet
+	case bool:
+		if e && actual != true {
+			return describe("be true")
+		}
+	case float64:
+		if n, ok := actual.(float64); !ok || n < e {
+			return describe(fmt.Sprintf("be at least %s", format(e)))
+		}
+	case []any:
+		present := valueSet(actual)
+		for _, item := range e {
+			if !present[key(item)] {
+				return describe(fmt.Sprintf("contain all of %s", format(e)))
+			}
+		}
+	case nil:
+	default:
+		if !reflect.DeepEqual(e, actual) {
+			return describe(fmt.Sprintf("be %s", format(e)))
+		}
+	}
+	return nil
+}
+
+// Convert a value decoded from YAML to the types produced by decoding JSON, so it
+// can be compared with values read from the entity
+func normalize(value any) any {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []any:
+		normalized := make([]any, len(v))
+		for i, item := range v {
+			normalized[i] = normalize(item)
+		}
+		return normalized
+	case map[any]any:
+		normalized := make(map[string]any, len(v))
+		for k, item := range v {
+			normalized[fmt.Sprint(k)] = normalize(item)
+		}
+		return normalized
+	default:
+		return v
+	}
+}
+
+func expectedItems(expected any) []any {
+	if items, ok := normalize(expected).([]any); ok {
+		return items
+	}
+	return []any{normalize(expected)}
+}
+
+func containsValue(expected any, value any) bool {
+	items, ok := expected.([]any)
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Index the elements of an array value so membership can be tested regardless of order
+func valueSet(value any) map[string]bool {
+	set := make(map[string]bool)
+	if items, ok := value.([]any); ok {
+		for _, item := range items {
+			set[key(item)] = true
+		}
+	}
+	return set
+}
+
+func key(value any) string {
+	bytes, _ := json.Marshal(value)
+	return string(bytes)
+}
+
+func format(value any) string {
+	bytes, err := json.Marshal(normalize(value))
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(bytes)
+}
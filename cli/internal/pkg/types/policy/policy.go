@@ -0,0 +1,263 @@
+package policy
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"gh_foundations/internal/pkg/types"
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/tidwall/gjson"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ITSG-33 control ids, optionally followed by a control enhancement
+var controlPattern = regexp.MustCompile(`^[A-Z]{2}-[0-9]+(\([0-9]+\))?$`)
+
+//go:embed default_policy.yaml
+var defaultPolicy []byte
+
+// Policy is a set of declarative rules evaluated by the check command
+type Policy struct {
+	Version int    `yaml:"version"`
+	Rules   []Rule `yaml:"rules"`
+}
+
+// Rule describes a check that reads fields of a GitHub entity and compares them to
+// expected values. A rule passes when all of its conditions pass. A single condition
+// can be written directly on the rule instead of under "conditions".
+type Rule struct {
+	Id         string   `yaml:"id"`
+	Entity     string   `yaml:"entity"`
+	Type       string   `yaml:"type"`
+	Title      string   `yaml:"title"`
+	Severity   string   `yaml:"severity"`
+	Guardrails []string `yaml:"guardrails"`
+	// The ITSG-33 security controls of the guardrails the rule assesses
+	Controls    []string `yaml:"controls"`
+	Remediation string   `yaml:"remediation"`
+	// Replaces the generated violation message when set
+	Message    string      `yaml:"message"`
+	Conditions []Condition `yaml:"conditions"`
+	// The rule only applies to the entities matching every condition, the others are
+	// reported as not applicable
+	When []Condition `yaml:"when"`
+
+	// Shorthand for a rule with a single condition, "conditions" then holds the nested
+	// conditions of the any, all and none operators
+	Field    string `yaml:"field"`
+	Operator string `yaml:"operator"`
+	Expected any    `yaml:"expected"`
+	Default  any    `yaml:"default"`
+}
+
+// Condition compares the value found at Field with Expected using Operator. Fields are
+// gjson paths (https://github.com/tidwall/gjson/blob/master/SYNTAX.md) into the JSON
+// representation of the entity, e.g. "security_and_analysis.secret_scanning.status".
+type Condition struct {
+	Field    string `yaml:"field"`
+	Operator string `yaml:"operator"`
+	Expected any    `yaml:"expected"`
+	// The value used when the field is missing from the entity
+	Default any `yaml:"default"`
+	// Conditions applied to the elements of an array by the any, all and none operators
+	Conditions []Condition `yaml:"conditions"`
+}
+
+// Load reads a policy from a YAML file
+func Load(path string) (*Policy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read policy file: %w", err)
+	}
+	policy, err := Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Default returns the policy implementing the GoC guardrails, shipped with the CLI
+func Default() *Policy {
+	policy, err := Parse(defaultPolicy)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default policy: %s", err))
+	}
+	return policy
+}
+
+// DefaultPolicyYAML returns the source of the default policy, as a starting point for custom policies
+func DefaultPolicyYAML() []byte {
+	return defaultPolicy
+}
+
+func Parse(content []byte) (*Policy, error) {
+	var policy Policy
+	if err := yaml.UnmarshalStrict(content, &policy); err != nil {
+		return nil, err
+	}
+	if policy.Version != 1 {
+		return nil, fmt.Errorf("unsupported policy version %d", policy.Version)
+	}
+
+	var allErrors error
+	ids := make(map[string]bool)
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		// Fold the shorthand single condition into the list of conditions. The conditions
+		// of a shorthand any, all or none operator are its nested conditions.
+		if rule.Field != "" || rule.Operator != "" {
+			shorthand := Condition{Field: rule.Field, Operator: rule.Operator, Expected: rule.Expected, Default: rule.Default}
+			if operators[rule.Operator].nested {
+				shorthand.Conditions = rule.Conditions
+				rule.Conditions = nil
+			}
+			rule.Conditions = append([]Condition{shorthand}, rule.Conditions...)
+		}
+
+		key := rule.Entity + "/" + rule.Id
+		if ids[key] {
+			allErrors = errors.Join(allErrors, fmt.Errorf("rule %q is defined more than once for %s", rule.Id, rule.Entity))
+		}
+		ids[key] = true
+		if err := rule.validate(); err != nil {
+			allErrors = errors.Join(allErrors, err)
+		}
+	}
+	if allErrors != nil {
+		return nil, allErrors
+	}
+	return &policy, nil
+}
+
+// Definition documents the check the rule is turned into
+func (r *Rule) Definition() types.CheckDefinition {
+	guardrails := make([]types.Guardrail, len(r.Guardrails))
+	for i, g := range r.Guardrails {
+		guardrails[i] = types.Guardrail(g)
+	}
+	checkType := types.CheckType(r.Type)
+	if checkType == "" {
+		checkType = types.GoCGuardrails
+	}
+	return types.CheckDefinition{
+		Id:          r.Id,
+		Type:        checkType,
+		Title:       r.Title,
+		Severity:    types.Severity(r.Severity),
+		Guardrails:  guardrails,
+		Controls:    r.Controls,
+		Remediation: r.Remediation,
+	}
+}
+
+// Evaluate the rule against the JSON representation of an entity. The returned error
+// describes the violation, or is types.ErrNotApplicable when the rule does not apply.
+func (r *Rule) Evaluate(document gjson.Result) error {
+	for _, c := range r.When {
+		if c.evaluate(document) != nil {
+			return types.ErrNotApplicable
+		}
+	}
+	var allErrors error
+	for _, c := range r.Conditions {
+		allErrors = errors.Join(allErrors, c.evaluate(document))
+	}
+	if allErrors != nil && r.Message != "" {
+		return errors.New(r.Message)
+	}
+	return allErrors
+}
+
+// Fields lists the fields read by the conditions and the when conditions of the rule.
+// The fields of nested conditions, relative to the elements of a list, follow the field
+// of the list, e.g. rulesets.type.
+func (r *Rule) Fields() []string {
+	var fields []string
+	var add func(prefix string, conditions []Condition)
+	add = func(prefix string, conditions []Condition) {
+		for _, c := range conditions {
+			field := prefix + c.Field
+			if !slices.Contains(fields, field) {
+				fields = append(fields, field)
+			}
+			add(field+".", c.Conditions)
+		}
+	}
+	add("", r.When)
+	add("", r.Conditions)
+	return fields
+}
+
+func (r *Rule) validate() error {
+	var allErrors error
+	if r.Id == "" {
+		allErrors = errors.Join(allErrors, errors.New("rules require an id"))
+	}
+	if r.Entity == "" {
+		allErrors = errors.Join(allErrors, fmt.Errorf("rule %q requires an entity", r.Id))
+	}
+	if r.Severity != "" && types.Severity(r.Severity).Rank() == 0 {
+		allErrors = errors.Join(allErrors, fmt.Errorf("rule %q has an unknown severity %q", r.Id, r.Severity))
+	}
+	for _, g := range r.Guardrails {
+		if !types.Guardrail(g).Valid() {
+			allErrors = errors.Join(allErrors, fmt.Errorf("rule %q maps to an unknown guardrail %q", r.Id, g))
+		}
+	}
+	for _, c := range r.Controls {
+		if !controlPattern.MatchString(c) {
+			allErrors = errors.Join(allErrors, fmt.Errorf("rule %q maps to an invalid control %q, expected a control id such as AC-2 or AC-2(7)", r.Id, c))
+		}
+	}
+	if len(r.Conditions) == 0 {
+		allErrors = errors.Join(allErrors, fmt.Errorf("rule %q has no conditions", r.Id))
+	}
+	for _, c := range append(r.Conditions, r.When...) {
+		if err := c.validate(); err != nil {
+			allErrors = errors.Join(allErrors, fmt.Errorf("rule %q: %w", r.Id, err))
+		}
+	}
+	return allErrors
+}
+
+func (c *Condition) validate() error {
+	if c.Field == "" {
+		return errors.New("conditions require a field")
+	}
+	op, ok := operators[c.Operator]
+	if !ok {
+		return fmt.Errorf("unknown operator %q for field %q", c.Operator, c.Field)
+	}
+	if op.nested {
+		if len(c.Conditions) == 0 {
+			return fmt.Errorf("operator %q for field %q requires conditions", c.Operator, c.Field)
+		}
+		for _, nested := range c.Conditions {
+			if err := nested.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Operator == "matches" {
+		pattern, ok := c.Expected.(string)
+		if !ok {
+			return fmt.Errorf("operator %q for field %q requires a regular expression", c.Operator, c.Field)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid regular expression for field %q: %w", c.Field, err)
+		}
+	}
+	return nil
+}
+
+func (c *Condition) evaluate(document gjson.Result) error {
+	actual := document.Get(c.Field)
+	value := actual.Value()
+	if !actual.Exists() && c.Default != nil {
+		value = normalize(c.Default)
+	}
+	return operators[c.Operator].evaluate(c, actual.Exists() || c.Default != nil, value, actual)
+}
@@ -0,0 +1,340 @@
+package policy
+
+import (
+	"gh_foundations/internal/pkg/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := Default()
+
+	assert.Equal(t, 1, policy.Version)
+	assert.Len(t, policy.Rules, 36)
+	for _, rule := range policy.Rules {
+		assert.NotEmpty(t, rule.Conditions, rule.Id)
+		assert.NotEmpty(t, rule.Guardrails, rule.Id)
+		assert.NotEmpty(t, rule.Controls, rule.Id)
+	}
+}
+
+func TestParseShorthandCondition(t *testing.T) {
+	policy, err := Parse([]byte(`
+version: 1
+rules:
+  - id: roles
+    entity: github_organization
+    field: roles
+    operator: any
+    conditions:
+      - field: name
+        operator: equals
+        expected: admin
+`))
+	require.NoError(t, err)
+
+	require.Len(t, policy.Rules[0].Conditions, 1)
+	condition := policy.Rules[0].Conditions[0]
+	assert.Equal(t, "roles", condition.Field)
+	assert.Equal(t, "any", condition.Operator)
+	require.Len(t, condition.Conditions, 1)
+	assert.Equal(t, "name", condition.Conditions[0].Field)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"version", "version: 2\nrules: []", "unsupported policy version 2"},
+		{"unknown key", "version: 1\nrules:\n  - id: a\n    entity: e\n    field: f\n    operator: exists\n    unknown: true", "field unknown not found"},
+		{"missing id", "version: 1\nrules:\n  - entity: e\n    field: f\n    operator: exists", "rules require an id"},
+		{"missing entity", "version: 1\nrules:\n  - id: a\n    field: f\n    operator: exists", `rule "a" requires an entity`},
+		{"duplicate", "version: 1\nrules:\n  - id: a\n    entity: e\n    field: f\n    operator: exists\n  - id: a\n    entity: e\n    field: f\n    operator: exists", `rule "a" is defined more than once for e`},
+		{"severity", "version: 1\nrules:\n  - id: a\n    entity: e\n    severity: urgent\n    field: f\n    operator: exists", `unknown severity "urgent"`},
+		{"guardrail", "version: 1\nrules:\n  - id: a\n    entity: e\n    guardrails: [\"12\"]\n    field: f\n    operator: exists", `unknown guardrail "12"`},
+		{"control", "version: 1\nrules:\n  - id: a\n    entity: e\n    controls: [\"ac-2\"]\n    field: f\n    operator: exists", `rule "a" maps to an invalid control "ac-2"`},
+		{"no conditions", "version: 1\nrules:\n  - id: a\n    entity: e", `rule "a" has no conditions`},
+		{"operator", "version: 1\nrules:\n  - id: a\n    entity: e\n    field: f\n    operator: like", `unknown operator "like"`},
+		{"nested", "version: 1\nrules:\n  - id: a\n    entity: e\n    field: f\n    operator: any", `operator "any" for field "f" requires conditions`},
+		{"regex", "version: 1\nrules:\n  - id: a\n    entity: e\n    field: f\n    operator: matches\n    expected: \"[\"", `invalid regular expression for field "f"`},
+		{"when", "version: 1\nrules:\n  - id: a\n    entity: e\n    field: f\n    operator: exists\n    when:\n      - field: g\n        operator: like", `unknown operator "like" for field "g"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("version: 1\nrules:\n  - id: a\n    entity: e\n    field: f\n    operator: exists"), 0644))
+
+	policy, err := Load(path)
+	require.NoError(t, err)
+	assert.Len(t, policy.Rules, 1)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "unable to read policy file")
+}
+
+func TestOperators(t *testing.T) {
+	document := gjson.Parse(`{
+		"enabled": true,
+		"count": 2,
+		"name": "octo-org",
+		"tags": ["a", "b"],
+		"status": {"value": "enabled"},
+		"empty": null,
+		"rules": {"pull_request": {"required_approving_review_count": 2, "require_code_owner_review": false}, "checks": ["build", "test"]},
+		"roles": [
+			{"name": "maintainer", "permissions": ["read", "write"]},
+			{"name": "reader", "permissions": ["read"]}
+		]
+	}`)
+
+	tests := []struct {
+		condition Condition
+		passes    bool
+	}{
+		{Condition{Field: "enabled", Operator: "equals", Expected: true}, true},
+		{Condition{Field: "enabled", Operator: "equals", Expected: false}, false},
+		{Condition{Field: "missing", Operator: "equals", Expected: false}, false},
+		{Condition{Field: "missing", Operator: "equals", Expected: false, Default: false}, true},
+		{Condition{Field: "count", Operator: "equals", Expected: 2}, true},
+		{Condition{Field: "status.value", Operator: "not_equals", Expected: "disabled"}, true},
+		{Condition{Field: "missing", Operator: "not_equals", Expected: "disabled"}, true},
+		{Condition{Field: "name", Operator: "in", Expected: []any{"octo-org", "other"}}, true},
+		{Condition{Field: "name", Operator: "in", Expected: []any{"other"}}, false},
+		{Condition{Field: "name", Operator: "not_in", Expected: []any{"other"}}, true},
+		{Condition{Field: "count", Operator: "min", Expected: 2}, true},
+		{Condition{Field: "count", Operator: "min", Expected: 3}, false},
+		{Condition{Field: "count", Operator: "max", Expected: 1}, false},
+		{Condition{Field: "missing", Operator: "max", Expected: 1}, false},
+		{Condition{Field: "tags", Operator: "contains_all", Expected: []any{"b", "a"}}, true},
+		{Condition{Field: "tags", Operator: "contains_all", Expected: []any{"a", "c"}}, false},
+		{Condition{Field: "tags", Operator: "contains_all", Expected: "a"}, true},
+		{Condition{Field: "tags", Operator: "contains_none", Expected: []any{"c"}}, true},
+		{Condition{Field: "tags", Operator: "contains_none", Expected: []any{"b"}}, false},
+		{Condition{Field: "name", Operator: "exists"}, true},
+		{Condition{Field: "empty", Operator: "exists"}, false},
+		{Condition{Field: "empty", Operator: "not_exists"}, true},
+		{Condition{Field: "name", Operator: "matches", Expected: "^octo-"}, true},
+		{Condition{Field: "name", Operator: "matches", Expected: "^org-"}, false},
+		{Condition{Field: "roles", Operator: "any", Conditions: []Condition{
+			{Field: "name", Operator: "equals", Expected: "maintainer"},
+			{Field: "permissions", Operator: "contains_all", Expected: []any{"write"}},
+		}}, true},
+		{Condition{Field: "roles", Operator: "any", Conditions: []Condition{
+			{Field: "name", Operator: "equals", Expected: "reader"},
+			{Field: "permissions", Operator: "contains_all", Expected: []any{"write"}},
+		}}, false},
+		{Condition{Field: "missing", Operator: "any", Conditions: []Condition{{Field: "name", Operator: "exists"}}}, false},
+		{Condition{Field: "roles", Operator: "all", Conditions: []Condition{{Field: "permissions", Operator: "contains_all", Expected: "read"}}}, true},
+		{Condition{Field: "roles", Operator: "all", Conditions: []Condition{{Field: "permissions", Operator: "contains_all", Expected: "write"}}}, false},
+		{Condition{Field: "roles", Operator: "none", Conditions: []Condition{{Field: "name", Operator: "equals", Expected: "admin"}}}, true},
+		{Condition{Field: "count", Operator: "at_least", Expected: 1}, true},
+		{Condition{Field: "rules", Operator: "at_least", Expected: map[any]any{
+			"pull_request": map[any]any{"required_approving_review_count": 1, "require_code_owner_review": false},
+			"checks":       []any{"test"},
+		}}, true},
+		{Condition{Field: "rules", Operator: "at_least", Expected: map[any]any{"pull_request": map[any]any{"require_code_owner_review": true}}}, false},
+		{Condition{Field: "rules", Operator: "at_least", Expected: map[any]any{"pull_request": map[any]any{"required_approving_review_count": 3}}}, false},
+		{Condition{Field: "rules", Operator: "at_least", Expected: map[any]any{"deletion": true}}, false},
+		{Condition{Field: "rules", Operator: "at_least", Expected: map[any]any{"checks": []any{"lint"}}}, false},
+		{Condition{Field: "missing", Operator: "at_least", Expected: map[any]any{}}, false},
+	}
+
+	for _, test := range tests {
+		err := test.condition.evaluate(document)
+		if test.passes {
+			assert.NoError(t, err, "%s %s %v", test.condition.Field, test.condition.Operator, test.condition.Expected)
+		} else {
+			assert.Error(t, err, "%s %s %v", test.condition.Field, test.condition.Operator, test.condition.Expected)
+		}
+	}
+}
+
+func TestViolationMessage(t *testing.T) {
+	document := gjson.Parse(`{"count": 1}`)
+
+	err := (&Condition{Field: "count", Operator: "min", Expected: 2}).evaluate(document)
+	assert.EqualError(t, err, "count is 1. Expected it to be at least 2")
+
+	err = (&Condition{Field: "name", Operator: "equals", Expected: "octo"}).evaluate(document)
+	assert.EqualError(t, err, `name is not set. Expected it to be "octo"`)
+
+	err = (&Condition{Field: "roles", Operator: "none", Conditions: []Condition{{Field: "admin", Operator: "equals", Expected: true}}}).evaluate(gjson.Parse(`{"roles": [{"name":"a","admin":true},{"name":"b","admin":false}]}`))
+	assert.EqualError(t, err, `roles is a list of 2 elements. Expected it to have no element matching every nested condition, found {"admin":true,"name":"a"}`)
+
+	err = (&Condition{Field: "rules", Operator: "at_least", Expected: map[any]any{
+		"pull_request": map[any]any{"required_approving_review_count": 1, "require_last_push_approval": true},
+		"deletion":     true,
+	}}).evaluate(gjson.Parse(`{"rules": {"pull_request": {"required_approving_review_count": 0, "require_last_push_approval": true}}}`))
+	assert.EqualError(t, err, "rules does not meet the requirements. Expected deletion to be true (found not set), pull_request.required_approving_review_count to be at least 1 (found 0)")
+
+	rule := Rule{Message: "custom message", Conditions: []Condition{{Field: "count", Operator: "equals", Expected: 2}}}
+	assert.EqualError(t, rule.Evaluate(document), "custom message")
+}
+
+func TestRuleWhen(t *testing.T) {
+	rule := Rule{
+		When:       []Condition{{Field: "visibility", Operator: "equals", Expected: "public"}},
+		Conditions: []Condition{{Field: "runners", Operator: "equals", Expected: []any{}}},
+	}
+
+	assert.ErrorIs(t, rule.Evaluate(gjson.Parse(`{"visibility": "private", "runners": ["a"]}`)), types.ErrNotApplicable)
+	assert.NoError(t, rule.Evaluate(gjson.Parse(`{"visibility": "public", "runners": []}`)))
+	assert.EqualError(t, rule.Evaluate(gjson.Parse(`{"visibility": "public", "runners": ["a"]}`)), `runners is ["a"]. Expected it to be []`)
+}
+
+func TestRuleFields(t *testing.T) {
+	rule := Rule{
+		When: []Condition{{Field: "visibility", Operator: "equals", Expected: "public"}},
+		Conditions: []Condition{
+			{Field: "rulesets", Operator: "any", Conditions: []Condition{{Field: "type", Operator: "equals", Expected: "deletion"}}},
+			{Field: "default_branch_rules.deletion", Operator: "equals", Expected: true},
+			{Field: "visibility", Operator: "not_equals", Expected: "internal"},
+		},
+	}
+
+	assert.Equal(t, []string{"visibility", "rulesets", "rulesets.type", "default_branch_rules.deletion"}, rule.Fields())
+}
+
+func TestDefaultPolicyActionsRules(t *testing.T) {
+	policy := Default()
+	org := gjson.Parse(`{"actions": {
+		"enabled_repositories": "all",
+		"allowed_actions": "all",
+		"default_workflow_permissions": "read",
+		"can_approve_pull_request_reviews": false,
+		"fork_pr_approval_policy": "first_time_contributors_new_to_github",
+		"public_runner_groups": []
+	}}`)
+
+	assert.EqualError(t, findRule(t, policy, "org_actions_allowed_actions").Evaluate(org), `actions.allowed_actions is "all". Expected it to be one of ["local_only","selected"]`)
+	assert.NoError(t, findRule(t, policy, "org_actions_default_workflow_permissions").Evaluate(org))
+	assert.NoError(t, findRule(t, policy, "org_actions_can_approve_pull_request_reviews").Evaluate(org))
+	assert.Error(t, findRule(t, policy, "org_actions_fork_pr_approval").Evaluate(org))
+	assert.NoError(t, findRule(t, policy, "org_actions_public_runner_groups").Evaluate(org))
+	assert.ErrorIs(t, findRule(t, policy, "org_actions_allowed_actions").Evaluate(gjson.Parse(`{"actions": {"enabled_repositories": "none"}}`)), types.ErrNotApplicable)
+	assert.Equal(t, types.CheckType(types.ActionsSecurity), findRule(t, policy, "org_actions_allowed_actions").Definition().Type)
+
+	public := gjson.Parse(`{"visibility": "public", "actions": {"enabled": true, "allowed_actions": "selected", "default_workflow_permissions": "write", "self_hosted_runners": ["build-1"]}}`)
+	assert.NoError(t, findRule(t, policy, "actions_allowed_actions").Evaluate(public))
+	assert.EqualError(t, findRule(t, policy, "actions_default_workflow_permissions").Evaluate(public), `actions.default_workflow_permissions is "write". Expected it to be "read"`)
+	assert.EqualError(t, findRule(t, policy, "public_repository_self_hosted_runners").Evaluate(public), `actions.self_hosted_runners is ["build-1"]. Expected it to be []`)
+
+	private := gjson.Parse(`{"visibility": "private", "actions": {"enabled": false}}`)
+	for _, id := range []string{"actions_allowed_actions", "actions_default_workflow_permissions", "actions_fork_pr_approval", "public_repository_self_hosted_runners"} {
+		assert.ErrorIs(t, findRule(t, policy, id).Evaluate(private), types.ErrNotApplicable, id)
+	}
+}
+
+func findRule(t *testing.T, policy *Policy, id string) *Rule {
+	for i := range policy.Rules {
+		if policy.Rules[i].Id == id {
+			return &policy.Rules[i]
+		}
+	}
+	t.Fatalf("rule %s not found", id)
+	return nil
+}
+
+func TestDefaultPolicyOrganizationRules(t *testing.T) {
+	policy := Default()
+	document := gjson.Parse(`{
+		"dependabot_alerts_enabled_for_new_repositories": true,
+		"members_can_create_private_repositories": false,
+		"custom_repository_roles": [
+			{"name": "Security Engineer", "base_role": "maintain", "permissions": ["write_code_scanning", "delete_alerts_code_scanning"]},
+			{"name": "Contractor", "base_role": "read", "permissions": ["manage_webhooks"]}
+		]
+	}`)
+
+	assert.NoError(t, findRule(t, policy, "dependabot_alerts_enabled_for_new_repositories").Evaluate(document))
+	assert.EqualError(t, findRule(t, policy, "members_can_create_private_repositories").Evaluate(document), "members_can_create_private_repositories is not enabled. Expected it to be enabled")
+	// Missing settings are treated as disabled
+	assert.NoError(t, findRule(t, policy, "members_can_create_public_repositories").Evaluate(document))
+	assert.NoError(t, findRule(t, policy, "security_engineer_role").Evaluate(document))
+	assert.EqualError(t, findRule(t, policy, "contractor_role").Evaluate(document), "contractor role undefined in the organization")
+}
+
+func TestDefaultPolicyIdentityRules(t *testing.T) {
+	policy := Default()
+	document := gjson.Parse(`{
+		"two_factor_requirement_enabled": true,
+		"saml_sso_enabled": null,
+		"default_repository_permission": "write",
+		"owners": ["alice", "bob", "carol", "dave", "erin", "frank"]
+	}`)
+
+	assert.NoError(t, findRule(t, policy, "two_factor_requirement_enabled").Evaluate(document))
+	assert.ErrorIs(t, findRule(t, policy, "saml_sso_enabled").Evaluate(document), types.ErrNotApplicable)
+	assert.EqualError(t, findRule(t, policy, "saml_sso_enabled").Evaluate(gjson.Parse(`{"saml_sso_enabled": false}`)), "saml_sso_enabled is false. Expected it to be true")
+	assert.EqualError(t, findRule(t, policy, "owners_count").Evaluate(document), "owners.# is 6. Expected it to be at most 5")
+	assert.EqualError(t, findRule(t, policy, "default_repository_permission").Evaluate(document), `default_repository_permission is "write". Expected it to be one of ["none","read"]`)
+	assert.EqualError(t, findRule(t, policy, "web_commit_signoff_required").Evaluate(document), "web_commit_signoff_required is not set. Expected it to be true")
+
+	assert.NoError(t, findRule(t, policy, "owners_count").Evaluate(gjson.Parse(`{"owners": ["alice", "bob"]}`)))
+	assert.ErrorIs(t, findRule(t, policy, "owners_count").Evaluate(gjson.Parse(`{}`)), types.ErrNotApplicable)
+}
+
+func TestDefaultPolicyRepositoryRules(t *testing.T) {
+	policy := Default()
+	document := gjson.Parse(`{
+		"delete_branch_on_merge": true,
+		"security_and_analysis": {"secret_scanning": {"status": "disabled"}},
+		"default_branch_rules": {
+			"deletion": true,
+			"pull_request": {
+				"required_approving_review_count": 2,
+				"dismiss_stale_reviews_on_push": true,
+				"require_code_owner_review": true,
+				"require_last_push_approval": true,
+				"required_review_thread_resolution": false
+			},
+			"sources": ["Organization"]
+		}
+	}`)
+
+	assert.NoError(t, findRule(t, policy, "delete_branch_on_merge").Evaluate(document))
+	assert.NoError(t, findRule(t, policy, "rulesets").Evaluate(document))
+	assert.EqualError(t, findRule(t, policy, "secret_scanning").Evaluate(document), "secret_scanning is not enabled. Expected it to be enabled")
+
+	document = gjson.Parse(`{"outside_admin_collaborators": ["mallory"]}`)
+	assert.EqualError(t, findRule(t, policy, "outside_collaborators_admin").Evaluate(document), `outside_admin_collaborators is ["mallory"]. Expected it to be []`)
+	assert.NoError(t, findRule(t, policy, "outside_collaborators_admin").Evaluate(gjson.Parse(`{"outside_admin_collaborators": []}`)))
+	assert.ErrorIs(t, findRule(t, policy, "outside_collaborators_admin").Evaluate(gjson.Parse(`{"outside_admin_collaborators": null}`)), types.ErrNotApplicable)
+
+	err := findRule(t, policy, "rulesets").Evaluate(gjson.Parse(`{"default_branch_rules": {"deletion": true}}`))
+	assert.EqualError(t, err, "default_branch_rules does not meet the requirements. Expected pull_request to be set (found not set)")
+}
+
+func TestDefaultPolicyTeamRules(t *testing.T) {
+	policy := Default()
+	document := gjson.Parse(`{
+		"privacy": "secret",
+		"maintainers": [{"login": "alice", "maintained_teams": 2}, {"login": "bob", "maintained_teams": 7}],
+		"admin_repositories": []
+	}`)
+
+	assert.EqualError(t, findRule(t, policy, "team_privacy").Evaluate(document), `privacy is "secret". Expected it to be "closed"`)
+	assert.NoError(t, findRule(t, policy, "team_maintainers").Evaluate(document))
+	assert.EqualError(t, findRule(t, policy, "maintainer_team_count").Evaluate(document), `maintainers is a list of 2 elements. Expected it to only have elements matching every nested condition, found {"login":"bob","maintained_teams":7}`)
+	assert.NoError(t, findRule(t, policy, "team_admin_repositories").Evaluate(document))
+
+	document = gjson.Parse(`{"maintainers": [], "admin_repositories": ["infra"]}`)
+	assert.EqualError(t, findRule(t, policy, "team_maintainers").Evaluate(document), "maintainers.# is 0. Expected it to be at least 1")
+	assert.EqualError(t, findRule(t, policy, "team_admin_repositories").Evaluate(document), `admin_repositories is ["infra"]. Expected it to be []`)
+}
@@ -0,0 +1,431 @@
+package remediation
+
+import (
+	"fmt"
+	"gh_foundations/internal/pkg/types"
+	githubfoundations "gh_foundations/internal/pkg/types/github_foundations"
+	"gh_foundations/internal/pkg/types/status"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const repositoryEntityType = "github_repository"
+
+// A fixer returns the repository input attribute to set, and its value, to resolve the
+// violation of a check. It returns false when the inputs cannot resolve it.
+type fixer func(repo *githubfoundations.RepositoryInput) (string, cty.Value, bool)
+
+// The repository checks that can be fixed by changing the inputs of the repository set.
+// Push protection is left out, as no input controls it: advance_security enables secret
+// scanning without it.
+var fixers = map[string]fixer{
+	"delete_branch_on_merge":      enable("delete_head_on_merge"),
+	"dependabot_security_updates": enable("dependabot_security_updates"),
+	"secret_scanning":             enable("advance_security"),
+	"rulesets":                    protectDefaultBranch,
+}
+
+func enable(input string) fixer {
+	return func(repo *githubfoundations.RepositoryInput) (string, cty.Value, bool) {
+		return input, cty.True, true
+	}
+}
+
+// The repository set module protects the branches listed in protected_branches
+func protectDefaultBranch(repo *githubfoundations.RepositoryInput) (string, cty.Value, bool) {
+	if repo.DefaultBranch == "" {
+		return "", cty.NilVal, false
+	}
+	branches := make([]cty.Value, 0, len(repo.ProtectedBranches)+1)
+	for _, b := range repo.ProtectedBranches {
+		if b == repo.DefaultBranch {
+			return "", cty.NilVal, false
+		}
+		branches = append(branches, cty.StringVal(b))
+	}
+	return "protected_branches", cty.TupleVal(append(branches, cty.StringVal(repo.DefaultBranch))), true
+}
+
+// Fix sets an input of a managed repository to resolve the violations of checks
+type Fix struct {
+	Project    string
+	Repository string
+	// The repositories/terragrunt.hcl file of the project's repository set
+	Path string
+	// The RepositoryInput attribute to set, e.g. delete_head_on_merge
+	Input    string
+	Value    cty.Value
+	CheckIds []string
+}
+
+// Violation is a failed check of a repository
+type Violation struct {
+	Repository string
+	CheckId    string
+	// Why the violation cannot be fixed, when the check has a fix
+	Reason string
+}
+
+// Plan lists the changes to the Terragrunt configuration that fix the violations found
+// in the repositories of an organization
+type Plan struct {
+	Fixes []Fix
+	// The violations of managed repositories that cannot be fixed by changing their inputs
+	Unfixable []Violation
+	// The repositories with violations that are not managed by the configuration, which
+	// must be imported before they can be fixed
+	Unmanaged []string
+
+	// The current and the fixed content of every changed file
+	original map[string][]byte
+	fixed    map[string][]byte
+}
+
+type managedRepository struct {
+	project string
+	path    string
+	input   *githubfoundations.RepositoryInput
+}
+
+// NewPlan finds the repository set entry of every repository with violations in the
+// managed repositories, and rewrites the inputs that fix them.
+func NewPlan(org string, reports []types.CheckReport, managed status.OrgSet) (*Plan, error) {
+	repos := indexManagedRepositories(org, managed)
+	plan := &Plan{original: make(map[string][]byte), fixed: make(map[string][]byte)}
+
+	sorted := append([]types.CheckReport{}, reports...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].EntityId < sorted[j].EntityId })
+	for _, report := range sorted {
+		if report.EntityType != repositoryEntityType {
+			continue
+		}
+		violations := make(map[string]bool)
+		for _, checkErr := range report.Errors {
+			for checkId := range checkErr.Violations {
+				violations[checkId] = true
+			}
+		}
+		if len(violations) == 0 {
+			continue
+		}
+
+		repo, ok := repos[strings.ToLower(report.EntityId)]
+		if !ok {
+			plan.Unmanaged = append(plan.Unmanaged, report.EntityId)
+			continue
+		}
+
+		fixes := make(map[string]int)
+		for _, checkId := range sortedKeys(violations) {
+			fix, ok := fixers[checkId]
+			if !ok {
+				plan.Unfixable = append(plan.Unfixable, Violation{Repository: report.EntityId, CheckId: checkId})
+				continue
+			}
+			input, value, ok := fix(repo.input)
+			if !ok {
+				plan.Unfixable = append(plan.Unfixable, Violation{Repository: report.EntityId, CheckId: checkId})
+				continue
+			}
+			// Checks fixed by the same input share a single change
+			if i, ok := fixes[input]; ok {
+				plan.Fixes[i].CheckIds = append(plan.Fixes[i].CheckIds, checkId)
+				continue
+			}
+			fixes[input] = len(plan.Fixes)
+			plan.Fixes = append(plan.Fixes, Fix{
+				Project:    repo.project,
+				Repository: repo.input.Name,
+				Path:       repo.path,
+				Input:      input,
+				Value:      value,
+				CheckIds:   []string{checkId},
+			})
+		}
+	}
+
+	// The repositories whose entry cannot be changed are left unfixed, instead of
+	// failing the whole plan
+	byPath := make(map[string][]Fix)
+	for _, fix := range plan.Fixes {
+		byPath[fix.Path] = append(byPath[fix.Path], fix)
+	}
+	unfixed := make(map[string]error)
+	for path, fixes := range byPath {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the repository set: %w", err)
+		}
+		fixed, failed, err := applyFixes(path, content, fixes)
+		if err != nil {
+			return nil, err
+		}
+		for name, err := range failed {
+			unfixed[path+"/"+name] = err
+		}
+		if fixed != nil {
+			plan.original[path] = content
+			plan.fixed[path] = fixed
+		}
+	}
+
+	fixes := plan.Fixes[:0]
+	for _, fix := range plan.Fixes {
+		err, ok := unfixed[fix.Path+"/"+fix.Repository]
+		if !ok {
+			fixes = append(fixes, fix)
+			continue
+		}
+		for _, checkId := range fix.CheckIds {
+			plan.Unfixable = append(plan.Unfixable, Violation{Repository: fix.Repository, CheckId: checkId, Reason: err.Error()})
+		}
+	}
+	plan.Fixes = fixes
+	sort.SliceStable(plan.Unfixable, func(i, j int) bool {
+		a, b := plan.Unfixable[i], plan.Unfixable[j]
+		if !strings.EqualFold(a.Repository, b.Repository) {
+			return strings.ToLower(a.Repository) < strings.ToLower(b.Repository)
+		}
+		return a.CheckId < b.CheckId
+	})
+	return plan, nil
+}
+
+// Paths lists the files changed by the plan, in order
+func (p *Plan) Paths() []string {
+	paths := make([]string, 0, len(p.fixed))
+	for path := range p.fixed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// WriteDiff writes the changes as a unified diff of every changed file
+func (p *Plan) WriteDiff(w io.Writer) error {
+	for _, path := range p.Paths() {
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(p.original[path])),
+			B:        difflib.SplitLines(string(p.fixed[path])),
+			FromFile: path,
+			ToFile:   path,
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply writes the changed files in place
+func (p *Plan) Apply() error {
+	for _, path := range p.Paths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, p.fixed[path], info.Mode().Perm()); err != nil {
+			return fmt.Errorf("unable to write the repository set: %w", err)
+		}
+	}
+	return nil
+}
+
+// The repositories of the organization, by lower cased name
+func indexManagedRepositories(org string, managed status.OrgSet) map[string]managedRepository {
+	repos := make(map[string]managedRepository)
+	for orgName, projects := range managed.OrgProjectSets {
+		if !strings.EqualFold(orgName, org) {
+			continue
+		}
+		for project, repoSet := range projects.RepositorySets {
+			path := projects.RepositorySetPaths[project]
+			for _, list := range [][]*githubfoundations.RepositoryInput{repoSet.PrivateRepositories, repoSet.PublicRepositories} {
+				for _, repo := range list {
+					repos[strings.ToLower(repo.Name)] = managedRepository{project: project, path: path, input: repo}
+				}
+			}
+		}
+	}
+	return repos
+}
+
+// applyFixes sets the inputs in the entries of the repository set file, replacing the
+// current value of an input or adding it to the entry, then formats the file. The
+// repositories whose entry cannot be changed are returned with the reason, and the file
+// is nil when none could be.
+func applyFixes(path string, content []byte, fixes []Fix) ([]byte, map[string]error, error) {
+	file, diags := hclwrite.ParseConfig(content, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, nil, fmt.Errorf("unable to parse the repository set: %w", diags)
+	}
+	attr := file.Body().GetAttribute("inputs")
+	if attr == nil {
+		return nil, nil, fmt.Errorf("%s has no inputs", path)
+	}
+	tokens := attr.Expr().BuildTokens(nil)
+
+	var names []string
+	byRepository := make(map[string][]Fix)
+	for _, fix := range fixes {
+		if _, ok := byRepository[fix.Repository]; !ok {
+			names = append(names, fix.Repository)
+		}
+		byRepository[fix.Repository] = append(byRepository[fix.Repository], fix)
+	}
+	failed := make(map[string]error)
+	for _, name := range names {
+		fixed, err := setRepositoryInputs(tokens, name, byRepository[name])
+		if err != nil {
+			failed[name] = fmt.Errorf("%s: %w", path, err)
+			continue
+		}
+		tokens = fixed
+	}
+	if len(failed) == len(names) {
+		return nil, failed, nil
+	}
+
+	file.Body().SetAttributeRaw("inputs", tokens)
+	fixed := hclwrite.Format(file.Bytes())
+	if _, diags := hclsyntax.ParseConfig(fixed, path, hcl.InitialPos); diags.HasErrors() {
+		return nil, nil, fmt.Errorf("unable to fix %s: %w", path, diags)
+	}
+	return fixed, failed, nil
+}
+
+// Set the inputs of the fixes in the entry of the repository, in the tokens of the inputs
+// object. The attributes of the entry are edited as the body of an HCL file.
+func setRepositoryInputs(tokens hclwrite.Tokens, name string, fixes []Fix) (hclwrite.Tokens, error) {
+	start, end, ok := findRepository(tokens, name)
+	if !ok {
+		return nil, fmt.Errorf("repository %s not found in the inputs", name)
+	}
+	entry := tokens[start:end]
+	if entry[0].Type != hclsyntax.TokenOBrace || entry[len(entry)-1].Type != hclsyntax.TokenCBrace {
+		return nil, fmt.Errorf("the entry of repository %s is not an object", name)
+	}
+
+	// Keep every attribute on its own line, as in a body
+	newline := &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")}
+	inner := append(hclwrite.Tokens{}, entry[1:len(entry)-1]...)
+	if len(inner) == 0 || inner[0].Type != hclsyntax.TokenNewline {
+		inner = append(hclwrite.Tokens{newline}, inner...)
+	}
+	if inner[len(inner)-1].Type != hclsyntax.TokenNewline {
+		inner = append(inner, newline)
+	}
+	body, diags := hclwrite.ParseConfig(inner.Bytes(), name, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("the entry of repository %s cannot be edited: %w", name, diags)
+	}
+	for _, fix := range fixes {
+		body.Body().SetAttributeValue(fix.Input, fix.Value)
+	}
+
+	fixed := append(hclwrite.Tokens{}, tokens[:start]...)
+	fixed = append(fixed, entry[0])
+	fixed = append(fixed, body.BuildTokens(nil)...)
+	fixed = append(fixed, entry[len(entry)-1])
+	return append(fixed, tokens[end:]...), nil
+}
+
+// The range of the tokens of the repository's entry in the private or public repositories
+// of the inputs object
+func findRepository(inputs hclwrite.Tokens, name string) (int, int, bool) {
+	for _, key := range []string{"private_repositories", "public_repositories"} {
+		start, end, ok := findItem(inputs, 0, len(inputs), key)
+		if !ok {
+			continue
+		}
+		if start, end, ok := findItem(inputs, start, end, name); ok {
+			return start, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// The range of the value tokens of the item of the object in tokens[start:end] with the
+// key, keys being compared without case like GitHub names
+func findItem(tokens hclwrite.Tokens, start, end int, key string) (int, int, bool) {
+	if end-start < 2 || tokens[start].Type != hclsyntax.TokenOBrace || tokens[end-1].Type != hclsyntax.TokenCBrace {
+		return 0, 0, false
+	}
+	for i := start + 1; i < end-1; i++ {
+		switch tokens[i].Type {
+		case hclsyntax.TokenNewline, hclsyntax.TokenComma, hclsyntax.TokenComment:
+			continue
+		}
+		keyStart := i
+		i = skipTo(tokens, i, end-1, hclsyntax.TokenEqual, hclsyntax.TokenColon)
+		itemKey := keyName(tokens[keyStart:i])
+		valueStart := i + 1
+		i = skipTo(tokens, valueStart, end-1, hclsyntax.TokenNewline, hclsyntax.TokenComma)
+		if itemKey != "" && strings.EqualFold(itemKey, key) {
+			return valueStart, i, true
+		}
+	}
+	return 0, 0, false
+}
+
+// The name of a bare or quoted object key, or "" for the other keys
+func keyName(tokens hclwrite.Tokens) string {
+	if len(tokens) == 1 && tokens[0].Type == hclsyntax.TokenIdent {
+		return string(tokens[0].Bytes)
+	}
+	if len(tokens) < 2 || tokens[0].Type != hclsyntax.TokenOQuote || tokens[len(tokens)-1].Type != hclsyntax.TokenCQuote {
+		return ""
+	}
+	var name string
+	for _, token := range tokens[1 : len(tokens)-1] {
+		if token.Type != hclsyntax.TokenQuotedLit {
+			return ""
+		}
+		name += string(token.Bytes)
+	}
+	return name
+}
+
+// The index of the first of the token types outside of nested brackets, quotes and
+// templates, from tokens[i] to the end
+func skipTo(tokens hclwrite.Tokens, i, end int, types ...hclsyntax.TokenType) int {
+	depth := 0
+	for ; i < end; i++ {
+		switch tokens[i].Type {
+		case hclsyntax.TokenOBrace, hclsyntax.TokenOBrack, hclsyntax.TokenOParen, hclsyntax.TokenOQuote,
+			hclsyntax.TokenOHeredoc, hclsyntax.TokenTemplateInterp, hclsyntax.TokenTemplateControl:
+			depth++
+			continue
+		case hclsyntax.TokenCBrace, hclsyntax.TokenCBrack, hclsyntax.TokenCParen, hclsyntax.TokenCQuote,
+			hclsyntax.TokenCHeredoc, hclsyntax.TokenTemplateSeqEnd:
+			depth--
+			continue
+		}
+		if depth == 0 && slices.Contains(types, tokens[i].Type) {
+			return i
+		}
+	}
+	return i
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,194 @@
+package remediation
+
+import (
+	"bytes"
+	"gh_foundations/internal/pkg/types"
+	githubfoundations "gh_foundations/internal/pkg/types/github_foundations"
+	"gh_foundations/internal/pkg/types/status"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const repositorySet = `include "root" {
+  path   = find_in_parent_folders()
+  expose = true
+}
+
+inputs = {
+  private_repositories = {
+    "octo-repo" = {
+      description          = "An example repository"
+      default_branch       = "main"
+      protected_branches   = ["release"]
+      delete_head_on_merge = false
+      topics               = []
+    }
+  }
+  public_repositories = {
+    docs = { description = "Documentation" }
+  }
+}
+`
+
+func testOrgSet(path string) status.OrgSet {
+	return status.OrgSet{OrgProjectSets: map[string]status.OrgProjectSet{
+		"octo-org": {
+			RepositorySets: map[string]githubfoundations.RepositorySetInput{
+				"project": {
+					PrivateRepositories: []*githubfoundations.RepositoryInput{{Name: "octo-repo", DefaultBranch: "main", ProtectedBranches: []string{"release"}}},
+					PublicRepositories:  []*githubfoundations.RepositoryInput{{Name: "docs"}},
+				},
+			},
+			RepositorySetPaths: map[string]string{"project": path},
+		},
+	}}
+}
+
+func repositoryReport(name string, violations ...string) types.CheckReport {
+	report := types.CheckReport{EntityType: "github_repository", EntityId: name, Checks: map[string]types.CheckResult{}}
+	checkErr := types.CheckError{Check: types.GoCGuardrails, Violations: map[string]string{}}
+	for _, v := range violations {
+		report.Checks[v] = types.Failed
+		checkErr.Violations[v] = v + " failed"
+	}
+	report.Errors = []types.CheckError{checkErr}
+	return report
+}
+
+func TestNewPlan(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terragrunt.hcl")
+	require.NoError(t, os.WriteFile(path, []byte(repositorySet), 0644))
+
+	reports := []types.CheckReport{
+		{EntityType: "github_organization", EntityId: "octo-org", Errors: []types.CheckError{{Violations: map[string]string{"saml_sso_enabled": "failed"}}}},
+		repositoryReport("Octo-Repo", "delete_branch_on_merge", "secret_scanning", "secret_scanning_push_protection", "rulesets", "outside_collaborators_admin"),
+		repositoryReport("docs", "delete_branch_on_merge"),
+		repositoryReport("unmanaged", "secret_scanning"),
+		repositoryReport("compliant"),
+	}
+	reports[4].Errors = nil
+
+	plan, err := NewPlan("Octo-Org", reports, testOrgSet(path))
+	require.NoError(t, err)
+
+	require.Len(t, plan.Fixes, 4)
+	assert.Equal(t, "delete_head_on_merge", plan.Fixes[0].Input)
+	assert.Equal(t, "octo-repo", plan.Fixes[0].Repository)
+	assert.Equal(t, "project", plan.Fixes[0].Project)
+	assert.Equal(t, "protected_branches", plan.Fixes[1].Input)
+	assert.Equal(t, []string{"secret_scanning"}, plan.Fixes[2].CheckIds)
+	assert.Equal(t, "docs", plan.Fixes[3].Repository)
+	assert.Equal(t, []Violation{
+		{Repository: "Octo-Repo", CheckId: "outside_collaborators_admin"},
+		{Repository: "Octo-Repo", CheckId: "secret_scanning_push_protection"},
+	}, plan.Unfixable)
+	assert.Equal(t, []string{"unmanaged"}, plan.Unmanaged)
+	assert.Equal(t, []string{path}, plan.Paths())
+
+	var diff bytes.Buffer
+	require.NoError(t, plan.WriteDiff(&diff))
+	assert.Contains(t, diff.String(), "-      delete_head_on_merge = false\n")
+	assert.Contains(t, diff.String(), "+      delete_head_on_merge = true\n")
+
+	require.NoError(t, plan.Apply())
+	fixed, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `include "root" {
+  path   = find_in_parent_folders()
+  expose = true
+}
+
+inputs = {
+  private_repositories = {
+    "octo-repo" = {
+      description          = "An example repository"
+      default_branch       = "main"
+      protected_branches   = ["release", "main"]
+      delete_head_on_merge = true
+      topics               = []
+      advance_security     = true
+    }
+  }
+  public_repositories = {
+    docs = {
+      description          = "Documentation"
+      delete_head_on_merge = true
+    }
+  }
+}
+`, string(fixed))
+}
+
+func TestNewPlanRepositoriesThatCannotBeFixed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terragrunt.hcl")
+	require.NoError(t, os.WriteFile(path, []byte(`inputs = {
+  private_repositories = {
+    # The repository is managed
+    octo-repo = {
+      description          = var.enabled ? "Enabled" : "Disabled"
+      delete_head_on_merge = false
+    }
+  }
+  public_repositories = {
+    docs = { description = "Documentation", topics = [] }
+  }
+}
+`), 0644))
+	orgSet := testOrgSet(path)
+	set := orgSet.OrgProjectSets["octo-org"].RepositorySets["project"]
+	set.PublicRepositories = append(set.PublicRepositories, &githubfoundations.RepositoryInput{Name: "site"})
+	orgSet.OrgProjectSets["octo-org"].RepositorySets["project"] = set
+
+	reports := []types.CheckReport{
+		repositoryReport("octo-repo", "delete_branch_on_merge"),
+		repositoryReport("docs", "delete_branch_on_merge"),
+		repositoryReport("site", "secret_scanning", "delete_branch_on_merge"),
+	}
+	plan, err := NewPlan("octo-org", reports, orgSet)
+	require.NoError(t, err)
+
+	require.Len(t, plan.Fixes, 1)
+	assert.Equal(t, "octo-repo", plan.Fixes[0].Repository)
+	require.Len(t, plan.Unfixable, 3)
+	assert.Equal(t, Violation{Repository: "docs", CheckId: "delete_branch_on_merge"}, Violation{Repository: plan.Unfixable[0].Repository, CheckId: plan.Unfixable[0].CheckId})
+	assert.Contains(t, plan.Unfixable[0].Reason, "the entry of repository docs cannot be edited")
+	assert.Equal(t, Violation{Repository: "site", CheckId: "delete_branch_on_merge", Reason: path + ": repository site not found in the inputs"}, plan.Unfixable[1])
+	assert.Equal(t, Violation{Repository: "site", CheckId: "secret_scanning", Reason: path + ": repository site not found in the inputs"}, plan.Unfixable[2])
+
+	require.NoError(t, plan.Apply())
+	fixed, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `inputs = {
+  private_repositories = {
+    # The repository is managed
+    octo-repo = {
+      description          = var.enabled ? "Enabled" : "Disabled"
+      delete_head_on_merge = true
+    }
+  }
+  public_repositories = {
+    docs = { description = "Documentation", topics = [] }
+  }
+}
+`, string(fixed))
+}
+
+func TestNewPlanWithoutViolations(t *testing.T) {
+	plan, err := NewPlan("octo-org", []types.CheckReport{repositoryReport("docs")}, testOrgSet("missing.hcl"))
+	require.NoError(t, err)
+
+	assert.Empty(t, plan.Fixes)
+	assert.Empty(t, plan.Paths())
+}
+
+func TestNewPlanInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terragrunt.hcl")
+	require.NoError(t, os.WriteFile(path, []byte("inputs = {"), 0644))
+
+	_, err := NewPlan("octo-org", []types.CheckReport{repositoryReport("docs", "delete_branch_on_merge")}, testOrgSet(path))
+	assert.ErrorContains(t, err, "unable to parse the repository set")
+}
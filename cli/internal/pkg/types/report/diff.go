@@ -0,0 +1,136 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"gh_foundations/internal/pkg/types"
+	"os"
+	"sort"
+)
+
+// EntityDiff lists how the violations of an entity changed between two reports, by check id
+type EntityDiff struct {
+	EntityType string `json:"entity_type"`
+	EntityId   string `json:"entity_id"`
+	// Violations only found in the new report
+	New []string `json:"new"`
+	// Violations of the old report whose check passed in the new report
+	Resolved []string `json:"resolved"`
+	// Violations found in both reports
+	Unchanged []string `json:"unchanged"`
+	// Violations of the old report whose check was not run in the new report, e.g. because
+	// the entity or the check was not selected, or could not be evaluated
+	Unchecked []string `json:"unchecked,omitempty"`
+}
+
+// Diff compares the violations of two sets of check reports
+type Diff struct {
+	Entities []EntityDiff `json:"entities"`
+}
+
+// ReadReports loads check reports written in the json format
+func ReadReports(path string) ([]types.CheckReport, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read check reports: %w", err)
+	}
+	var reports []types.CheckReport
+	if err := json.Unmarshal(content, &reports); err != nil {
+		return nil, fmt.Errorf("invalid check reports %s: %w", path, err)
+	}
+	return reports, nil
+}
+
+type entityKey struct {
+	entityType string
+	entityId   string
+}
+
+// NewDiff compares the violations of every entity found in either set of reports.
+// Entities without any violation in both are left out.
+func NewDiff(old []types.CheckReport, new []types.CheckReport) Diff {
+	oldReports := indexReports(old)
+	newReports := indexReports(new)
+
+	keys := make([]entityKey, 0, len(oldReports)+len(newReports))
+	for key := range oldReports {
+		keys = append(keys, key)
+	}
+	for key := range newReports {
+		if _, ok := oldReports[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].entityType != keys[j].entityType {
+			return keys[i].entityType < keys[j].entityType
+		}
+		return keys[i].entityId < keys[j].entityId
+	})
+
+	diff := Diff{Entities: []EntityDiff{}}
+	for _, key := range keys {
+		oldReport, newReport := oldReports[key], newReports[key]
+		oldViolations, newViolations := violationIds(oldReport), violationIds(newReport)
+		entity := EntityDiff{EntityType: key.entityType, EntityId: key.entityId, New: []string{}, Resolved: []string{}, Unchanged: []string{}}
+
+		for _, checkId := range sortedIds(newViolations) {
+			if oldViolations[checkId] {
+				entity.Unchanged = append(entity.Unchanged, checkId)
+			} else {
+				entity.New = append(entity.New, checkId)
+			}
+		}
+		for _, checkId := range sortedIds(oldViolations) {
+			if newViolations[checkId] {
+				continue
+			}
+			if result, checked := newReport.Checks[checkId]; checked && result != types.Errored {
+				entity.Resolved = append(entity.Resolved, checkId)
+			} else {
+				entity.Unchecked = append(entity.Unchecked, checkId)
+			}
+		}
+
+		if len(oldViolations) > 0 || len(newViolations) > 0 {
+			diff.Entities = append(diff.Entities, entity)
+		}
+	}
+	return diff
+}
+
+// Regressions counts the violations only found in the new report
+func (d Diff) Regressions() int {
+	n := 0
+	for _, e := range d.Entities {
+		n += len(e.New)
+	}
+	return n
+}
+
+func indexReports(reports []types.CheckReport) map[entityKey]types.CheckReport {
+	index := make(map[entityKey]types.CheckReport, len(reports))
+	for _, report := range reports {
+		index[entityKey{report.EntityType, report.EntityId}] = report
+	}
+	return index
+}
+
+func violationIds(report types.CheckReport) map[string]bool {
+	ids := make(map[string]bool)
+	for _, checkErr := range report.Errors {
+		for checkId := range checkErr.Violations {
+			ids[checkId] = true
+		}
+	}
+	return ids
+}
+
+func sortedIds(ids map[string]bool) []string {
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"encoding/json"
+	"gh_foundations/internal/pkg/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDiff(t *testing.T) {
+	newReports := []types.CheckReport{
+		{
+			EntityType: "github_repository",
+			EntityId:   "octo-repo",
+			Checks:     map[string]types.CheckResult{"secret_scanning": types.Failed, "delete_branch_on_merge": types.Passed},
+			Errors: []types.CheckError{{
+				Check:      types.GoCGuardrails,
+				Violations: map[string]string{"secret_scanning": "secret_scanning is not enabled. Expected it to be enabled"},
+			}},
+		},
+		{
+			EntityType: "github_repository",
+			EntityId:   "octo-org/new-repo",
+			Checks:     map[string]types.CheckResult{"delete_branch_on_merge": types.Failed},
+			Errors: []types.CheckError{{
+				Check:      types.GoCGuardrails,
+				Violations: map[string]string{"delete_branch_on_merge": "delete_branch_on_merge is not enabled. Expected it to be enabled"},
+			}},
+		},
+	}
+
+	diff := NewDiff(testReports, newReports)
+
+	assert.Equal(t, []EntityDiff{
+		{EntityType: "github_repository", EntityId: "octo-org/new-repo", New: []string{"delete_branch_on_merge"}, Resolved: []string{}, Unchanged: []string{}},
+		{EntityType: "github_repository", EntityId: "octo-repo", New: []string{}, Resolved: []string{"delete_branch_on_merge"}, Unchanged: []string{"secret_scanning"}},
+		{EntityType: "github_repository", EntityId: "other-repo", New: []string{}, Resolved: []string{}, Unchanged: []string{}, Unchecked: []string{"secret_scanning"}},
+	}, diff.Entities)
+	assert.Equal(t, 1, diff.Regressions())
+
+	assert.Equal(t, 0, NewDiff(newReports, newReports).Regressions())
+}
+
+func TestReadReports(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "check_results.json")
+	content, err := json.Marshal(testReports)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	reports, err := ReadReports(path)
+	require.NoError(t, err)
+	assert.Equal(t, testReports, reports)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"entity_id": "octo-org"}`), 0644))
+	_, err = ReadReports(path)
+	assert.ErrorContains(t, err, "invalid check reports")
+}
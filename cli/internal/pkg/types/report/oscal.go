@@ -0,0 +1,285 @@
+package report
+
+import (
+	"fmt"
+	"gh_foundations/internal/pkg/types"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	oscalVersion = "1.1.2"
+	// The namespace of the properties specific to the checks
+	oscalNamespace = toolInformation
+	// The default policy the checks are planned by
+	oscalDefaultPolicy = toolInformation + "/blob/main/cli/internal/pkg/types/policy/default_policy.yaml"
+)
+
+// The namespace subject uuids are derived from, so an entity keeps the same uuid
+// across documents
+var oscalSubjectNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte(toolInformation))
+
+// The subset of the OSCAL 1.1.2 assessment results model used to report check
+// violations (https://pages.nist.gov/OSCAL/reference/1.1.2/assessment-results/json-reference/)
+type OscalDocument struct {
+	AssessmentResults OscalAssessmentResults `json:"assessment-results"`
+}
+
+type OscalAssessmentResults struct {
+	Uuid       string          `json:"uuid"`
+	Metadata   OscalMetadata   `json:"metadata"`
+	ImportAp   OscalImportAp   `json:"import-ap"`
+	Results    []OscalResult   `json:"results"`
+	BackMatter OscalBackMatter `json:"back-matter"`
+}
+
+type OscalMetadata struct {
+	Title        string `json:"title"`
+	LastModified string `json:"last-modified"`
+	Version      string `json:"version"`
+	OscalVersion string `json:"oscal-version"`
+}
+
+// The assessment plan the results belong to
+type OscalImportAp struct {
+	Href string `json:"href"`
+}
+
+type OscalBackMatter struct {
+	Resources []OscalResource `json:"resources"`
+}
+
+type OscalResource struct {
+	Uuid        string      `json:"uuid"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Rlinks      []OscalLink `json:"rlinks"`
+}
+
+type OscalLink struct {
+	Href string `json:"href"`
+}
+
+type OscalResult struct {
+	Uuid             string                 `json:"uuid"`
+	Title            string                 `json:"title"`
+	Description      string                 `json:"description"`
+	Start            string                 `json:"start"`
+	LocalDefinitions *OscalLocalDefinitions `json:"local-definitions,omitempty"`
+	ReviewedControls OscalReviewedControls  `json:"reviewed-controls"`
+	Observations     []OscalObservation     `json:"observations,omitempty"`
+	Findings         []OscalFinding         `json:"findings,omitempty"`
+}
+
+type OscalLocalDefinitions struct {
+	InventoryItems []OscalInventoryItem `json:"inventory-items"`
+}
+
+type OscalInventoryItem struct {
+	Uuid        string          `json:"uuid"`
+	Description string          `json:"description"`
+	Props       []OscalProperty `json:"props"`
+}
+
+type OscalReviewedControls struct {
+	ControlSelections []OscalControlSelection `json:"control-selections"`
+}
+
+type OscalControlSelection struct {
+	IncludeControls []OscalSelectControl `json:"include-controls,omitempty"`
+}
+
+type OscalSelectControl struct {
+	ControlId string `json:"control-id"`
+}
+
+type OscalObservation struct {
+	Uuid        string          `json:"uuid"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Props       []OscalProperty `json:"props,omitempty"`
+	Methods     []string        `json:"methods"`
+	Types       []string        `json:"types"`
+	Subjects    []OscalSubject  `json:"subjects"`
+	Collected   string          `json:"collected"`
+}
+
+type OscalSubject struct {
+	SubjectUuid string `json:"subject-uuid"`
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+}
+
+type OscalFinding struct {
+	Uuid                string                    `json:"uuid"`
+	Title               string                    `json:"title"`
+	Description         string                    `json:"description"`
+	Props               []OscalProperty           `json:"props,omitempty"`
+	Target              OscalFindingTarget        `json:"target"`
+	RelatedObservations []OscalRelatedObservation `json:"related-observations"`
+}
+
+type OscalFindingTarget struct {
+	Type     string            `json:"type"`
+	TargetId string            `json:"target-id"`
+	Status   OscalTargetStatus `json:"status"`
+}
+
+type OscalTargetStatus struct {
+	State string `json:"state"`
+}
+
+type OscalRelatedObservation struct {
+	ObservationUuid string `json:"observation-uuid"`
+}
+
+type OscalProperty struct {
+	Name  string `json:"name"`
+	Ns    string `json:"ns,omitempty"`
+	Value string `json:"value"`
+}
+
+// NewOscalDocument converts the check reports into an OSCAL assessment results document,
+// with one result per entity. Every violation is an observation of the entity, and a
+// finding of each control its check maps to, which the entity does not satisfy.
+// Violations of checks without controls are only observations.
+func NewOscalDocument(reports []types.CheckReport, opts Options) OscalDocument {
+	definitions := make(map[string]types.CheckDefinition)
+	for _, def := range opts.Definitions {
+		definitions[def.EntityType+"/"+def.Id] = def
+	}
+
+	results := make([]OscalResult, 0, len(reports))
+	for _, report := range reports {
+		results = append(results, newOscalResult(report, definitions))
+	}
+	plan := newOscalPlanResource(opts.Policy)
+
+	return OscalDocument{AssessmentResults: OscalAssessmentResults{
+		Uuid: uuid.NewString(),
+		Metadata: OscalMetadata{
+			Title:        "GitHub Foundations guardrail checks",
+			LastModified: time.Now().UTC().Format(time.RFC3339),
+			Version:      "1.0",
+			OscalVersion: oscalVersion,
+		},
+		// The checks are planned by their policy rather than an assessment plan
+		// document, which the back matter links to
+		ImportAp:   OscalImportAp{Href: "#" + plan.Uuid},
+		Results:    results,
+		BackMatter: OscalBackMatter{Resources: []OscalResource{plan}},
+	}}
+}
+
+// The resource of the policy the checks were run against, which keeps its uuid
+// across documents
+func newOscalPlanResource(policy string) OscalResource {
+	href := oscalDefaultPolicy
+	if policy != "" {
+		href = policy
+	}
+	return OscalResource{
+		Uuid:        uuid.NewSHA1(oscalSubjectNamespace, []byte("policy/"+href)).String(),
+		Title:       "Guardrail checks policy",
+		Description: "The policy defining the checks assessed by the results",
+		Rlinks:      []OscalLink{{Href: href}},
+	}
+}
+
+func newOscalResult(report types.CheckReport, definitions map[string]types.CheckDefinition) OscalResult {
+	entity := fmt.Sprintf("%s %s", report.EntityType, report.EntityId)
+	subject := OscalSubject{
+		SubjectUuid: uuid.NewSHA1(oscalSubjectNamespace, []byte(report.EntityType+"/"+report.EntityId)).String(),
+		Type:        "inventory-item",
+		Title:       entity,
+	}
+	result := OscalResult{
+		Uuid:        uuid.NewString(),
+		Title:       entity,
+		Description: fmt.Sprintf("Guardrail checks of %s", entity),
+		Start:       report.Timestamp,
+		LocalDefinitions: &OscalLocalDefinitions{InventoryItems: []OscalInventoryItem{{
+			Uuid:        subject.SubjectUuid,
+			Description: entity,
+			Props: []OscalProperty{
+				{Name: "entity-type", Ns: oscalNamespace, Value: report.EntityType},
+				{Name: "entity-id", Ns: oscalNamespace, Value: report.EntityId},
+			},
+		}}},
+	}
+
+	// The controls of every check run, whether it passed or not, unless it could not be evaluated
+	reviewed := make(map[string]bool)
+	for checkId, checkResult := range report.Checks {
+		if checkResult == types.Errored {
+			continue
+		}
+		for _, control := range definitions[report.EntityType+"/"+checkId].Controls {
+			reviewed[control] = true
+		}
+	}
+	controls := make([]OscalSelectControl, 0, len(reviewed))
+	for _, control := range sortedIds(reviewed) {
+		controls = append(controls, OscalSelectControl{ControlId: oscalControlId(control)})
+	}
+	result.ReviewedControls = OscalReviewedControls{ControlSelections: []OscalControlSelection{{IncludeControls: controls}}}
+
+	for _, checkErr := range report.Errors {
+		for _, checkId := range sortedKeys(checkErr.Violations) {
+			def, ok := definitions[report.EntityType+"/"+checkId]
+			if !ok {
+				def = types.CheckDefinition{Id: checkId, Type: checkErr.Check, EntityType: report.EntityType}
+			}
+			title := def.Title
+			if title == "" {
+				title = checkId
+			}
+			props := []OscalProperty{{Name: "check-id", Ns: oscalNamespace, Value: checkId}}
+			for _, g := range def.Guardrails {
+				props = append(props, OscalProperty{Name: "guardrail", Ns: oscalNamespace, Value: string(g)})
+			}
+
+			observation := OscalObservation{
+				Uuid:        uuid.NewString(),
+				Title:       title,
+				Description: checkErr.Violations[checkId],
+				Props:       props,
+				Methods:     []string{"TEST"},
+				Types:       []string{"finding"},
+				Subjects:    []OscalSubject{subject},
+				Collected:   report.Timestamp,
+			}
+			result.Observations = append(result.Observations, observation)
+
+			for _, control := range def.Controls {
+				result.Findings = append(result.Findings, OscalFinding{
+					Uuid:        uuid.NewString(),
+					Title:       fmt.Sprintf("%s: %s", control, title),
+					Description: fmt.Sprintf("%s: %s", entity, checkErr.Violations[checkId]),
+					Props:       props,
+					Target: OscalFindingTarget{
+						Type:     "objective-id",
+						TargetId: oscalControlId(control) + "_obj",
+						Status:   OscalTargetStatus{State: "not-satisfied"},
+					},
+					RelatedObservations: []OscalRelatedObservation{{ObservationUuid: observation.Uuid}},
+				})
+			}
+		}
+	}
+	sort.SliceStable(result.Findings, func(i, j int) bool {
+		return result.Findings[i].Target.TargetId < result.Findings[j].Target.TargetId
+	})
+	return result
+}
+
+// oscalControlId converts an ITSG-33 control id to the id of the control in OSCAL
+// catalogs, e.g. AC-2(7) to ac-2.7
+func oscalControlId(control string) string {
+	id := strings.ToLower(control)
+	id = strings.ReplaceAll(id, "(", ".")
+	return strings.ReplaceAll(id, ")", "")
+}
@@ -0,0 +1,79 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"gh_foundations/internal/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOscalDocument(t *testing.T) {
+	reports := make([]types.CheckReport, len(testReports))
+	copy(reports, testReports)
+	reports[1].Timestamp = "2024-05-01T12:00:00Z"
+
+	doc := NewOscalDocument(reports, Options{Definitions: testDefinitions})
+	ar := doc.AssessmentResults
+
+	assert.Equal(t, "1.1.2", ar.Metadata.OscalVersion)
+	require.Len(t, ar.BackMatter.Resources, 1)
+	plan := ar.BackMatter.Resources[0]
+	assert.Equal(t, "#"+plan.Uuid, ar.ImportAp.Href)
+	assert.Equal(t, []OscalLink{{Href: oscalDefaultPolicy}}, plan.Rlinks)
+	require.Len(t, ar.Results, 2)
+
+	result := ar.Results[1]
+	assert.Equal(t, "github_repository other-repo", result.Title)
+	assert.Equal(t, "2024-05-01T12:00:00Z", result.Start)
+	assert.Equal(t, []OscalSelectControl{{ControlId: "ia-5.7"}, {ControlId: "sc-12"}}, result.ReviewedControls.ControlSelections[0].IncludeControls)
+
+	require.Len(t, result.Observations, 1)
+	observation := result.Observations[0]
+	assert.Equal(t, "Secret scanning is enabled", observation.Title)
+	assert.Equal(t, "secret_scanning is not enabled. Expected it to be enabled", observation.Description)
+	assert.Equal(t, "2024-05-01T12:00:00Z", observation.Collected)
+	assert.Equal(t, []string{"TEST"}, observation.Methods)
+	require.Len(t, observation.Subjects, 1)
+	assert.Equal(t, result.LocalDefinitions.InventoryItems[0].Uuid, observation.Subjects[0].SubjectUuid)
+
+	require.Len(t, result.Findings, 2)
+	finding := result.Findings[0]
+	assert.Equal(t, OscalFindingTarget{Type: "objective-id", TargetId: "ia-5.7_obj", Status: OscalTargetStatus{State: "not-satisfied"}}, finding.Target)
+	assert.Equal(t, []OscalRelatedObservation{{ObservationUuid: observation.Uuid}}, finding.RelatedObservations)
+	assert.Contains(t, finding.Props, OscalProperty{Name: "guardrail", Ns: oscalNamespace, Value: "05"})
+	assert.Equal(t, "sc-12_obj", result.Findings[1].Target.TargetId)
+
+	// delete_branch_on_merge has no controls, its violation is only observed
+	result = ar.Results[0]
+	assert.Len(t, result.Observations, 2)
+	assert.Len(t, result.Findings, 2)
+
+	// Subjects keep their uuid across documents
+	other := NewOscalDocument(reports, Options{Definitions: testDefinitions})
+	assert.Equal(t, observation.Subjects[0].SubjectUuid, other.AssessmentResults.Results[1].Observations[0].Subjects[0].SubjectUuid)
+	assert.NotEqual(t, ar.Uuid, other.AssessmentResults.Uuid)
+	assert.Equal(t, ar.ImportAp, other.AssessmentResults.ImportAp)
+
+	// A custom policy is linked instead of the default one
+	custom := NewOscalDocument(reports, Options{Definitions: testDefinitions, Policy: "policy.yaml"})
+	assert.Equal(t, []OscalLink{{Href: "policy.yaml"}}, custom.AssessmentResults.BackMatter.Resources[0].Rlinks)
+	assert.NotEqual(t, ar.ImportAp, custom.AssessmentResults.ImportAp)
+}
+
+func TestWriteOscal(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatOSCAL, testReports, Options{Definitions: testDefinitions}))
+
+	var doc map[string]map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Contains(t, doc, "assessment-results")
+	assert.Equal(t, "oscal.json", FormatOSCAL.Extension())
+}
+
+func TestOscalControlId(t *testing.T) {
+	assert.Equal(t, "ac-2", oscalControlId("AC-2"))
+	assert.Equal(t, "ac-2.7", oscalControlId("AC-2(7)"))
+}
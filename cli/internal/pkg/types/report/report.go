@@ -0,0 +1,90 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"gh_foundations/internal/pkg/types"
+	"io"
+	"strings"
+)
+
+// Format is an output format for check reports
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatSarif    Format = "sarif"
+	FormatTable    Format = "table"
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+	FormatOSCAL    Format = "oscal"
+)
+
+var Formats = []Format{FormatJSON, FormatSarif, FormatTable, FormatMarkdown, FormatHTML, FormatOSCAL}
+
+func ParseFormat(value string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(value) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q, expected one of %s", value, FormatNames())
+}
+
+func FormatNames() string {
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, "|")
+}
+
+// The file extension conventionally used for the format
+func (f Format) Extension() string {
+	switch f {
+	case FormatTable:
+		return "txt"
+	case FormatMarkdown:
+		return "md"
+	case FormatOSCAL:
+		return "oscal.json"
+	default:
+		return string(f)
+	}
+}
+
+// Options holds the context needed to render reports beyond the check results
+type Options struct {
+	// The definitions of the checks that were run
+	Definitions []types.CheckDefinition
+	// The web URL of the GitHub instance the entities belong to, e.g. https://github.com/
+	WebURL string
+	// The organization the entities belong to, which qualifies the bare repository names
+	Organization string
+	// The policy file defining the checks, empty for the default policy
+	Policy string
+}
+
+// Write renders the check reports in the given format
+func Write(w io.Writer, format Format, reports []types.CheckReport, opts Options) error {
+	switch format {
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(reports)
+	case FormatSarif:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(NewSarifLog(reports, opts))
+	case FormatOSCAL:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(NewOscalDocument(reports, opts))
+	case FormatTable:
+		return writeTable(w, NewSummary(reports, opts.Definitions))
+	case FormatMarkdown:
+		return markdownTemplate.Execute(w, NewSummary(reports, opts.Definitions))
+	case FormatHTML:
+		return htmlTemplate.Execute(w, NewSummary(reports, opts.Definitions))
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
@@ -0,0 +1,124 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"gh_foundations/internal/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("Markdown")
+	require.NoError(t, err)
+	assert.Equal(t, FormatMarkdown, format)
+	assert.Equal(t, "md", format.Extension())
+
+	_, err = ParseFormat("xml")
+	assert.EqualError(t, err, `unknown format "xml", expected one of json|sarif|table|markdown|html|oscal`)
+}
+
+func TestNewSummary(t *testing.T) {
+	summary := NewSummary(testReports, testDefinitions)
+
+	assert.Equal(t, 1, summary.Passed)
+	assert.Equal(t, 3, summary.Failed)
+	assert.Equal(t, []GuardrailSummary{
+		{Guardrail: types.DataProtection, Passed: 0, Failed: 2},
+		{Guardrail: types.CyberDefenseServices, Passed: 1, Failed: 3},
+	}, summary.Guardrails)
+	assert.Equal(t, []EntitySummary{
+		{EntityType: "github_repository", EntityId: "octo-repo", Passed: 0, Failed: 2},
+		{EntityType: "github_repository", EntityId: "other-repo", Passed: 1, Failed: 1},
+	}, summary.Entities)
+
+	require.Len(t, summary.Violations, 3)
+	assert.Equal(t, "secret_scanning", summary.Violations[0].CheckId)
+	assert.Equal(t, "secret_scanning", summary.Violations[1].CheckId)
+	assert.Equal(t, "delete_branch_on_merge", summary.Violations[2].CheckId)
+	assert.Equal(t, types.SeverityLow, summary.Violations[2].Severity)
+}
+
+func TestWrite(t *testing.T) {
+	opts := Options{Definitions: testDefinitions}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatSarif, testReports, opts))
+	var log map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, "https://json.schemastore.org/sarif-2.1.0.json", log["$schema"])
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatJSON, testReports, opts))
+	var reports []map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &reports))
+	assert.Len(t, reports, 2)
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatTable, testReports, opts))
+	assert.Contains(t, buf.String(), "1 checks passed, 3 checks failed")
+	assert.Regexp(t, `other-repo\s+secret_scanning\s+high\s+05,07\s+secret_scanning is not enabled`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatMarkdown, testReports, opts))
+	assert.Contains(t, buf.String(), "| [05 Data Protection](https://github.com/canada-ca/fondations-github-foundations/blob/main/guardrails/EN/05_Data-Protection.md) | 0 | 2 |")
+	assert.Contains(t, buf.String(), "| octo-repo | github_repository | 0 | 2 |")
+	assert.Contains(t, buf.String(), "| octo-repo | `delete_branch_on_merge` | low | 07 | delete_branch_on_merge is not enabled. Expected it to be enabled |")
+
+	buf.Reset()
+	escaped := []types.CheckReport{{
+		EntityType: "github_repository",
+		EntityId:   "<repo>",
+		Checks:     map[string]types.CheckResult{"secret_scanning": types.Failed},
+		Errors:     []types.CheckError{{Check: types.GoCGuardrails, Violations: map[string]string{"secret_scanning": "a | b"}}},
+	}}
+	require.NoError(t, Write(&buf, FormatHTML, escaped, opts))
+	assert.Contains(t, buf.String(), "<td>&lt;repo&gt;</td><td>github_repository</td>")
+	assert.Contains(t, buf.String(), "<td>a | b</td>")
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatMarkdown, escaped, opts))
+	assert.Contains(t, buf.String(), `| a \| b |`)
+}
+
+func TestCountViolations(t *testing.T) {
+	summary := NewSummary(testReports, testDefinitions)
+
+	assert.Equal(t, 3, summary.CountViolations(""))
+	assert.Equal(t, 3, summary.CountViolations(types.SeverityLow))
+	assert.Equal(t, 2, summary.CountViolations(types.SeverityHigh))
+	assert.Equal(t, 0, summary.CountViolations(types.SeverityCritical))
+}
+
+func TestErroredChecks(t *testing.T) {
+	opts := Options{Definitions: testDefinitions}
+	reports := []types.CheckReport{{
+		EntityType: "github_organization",
+		EntityId:   "octo-org",
+		Checks:     map[string]types.CheckResult{"saml_sso_enabled": types.Errored},
+		Errors: []types.CheckError{{
+			Check:      types.GoCGuardrails,
+			Violations: map[string]string{},
+			Errored:    map[string]string{"saml_sso_enabled": "check could not be evaluated: forbidden"},
+		}},
+	}}
+
+	summary := NewSummary(reports, testDefinitions)
+	assert.Equal(t, 0, summary.Failed)
+	assert.Equal(t, 1, summary.Errored)
+	assert.Empty(t, summary.Violations)
+	require.Len(t, summary.Errors, 1)
+	assert.Equal(t, "saml_sso_enabled", summary.Errors[0].CheckId)
+	assert.Equal(t, 0, summary.CountViolations(""))
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatTable, reports, opts))
+	assert.Contains(t, buf.String(), "0 checks passed, 0 checks failed, 1 checks could not be evaluated")
+	assert.Regexp(t, `octo-org\s+saml_sso_enabled\s+check could not be evaluated: forbidden`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatMarkdown, reports, opts))
+	assert.Contains(t, buf.String(), "| octo-org | `saml_sso_enabled` | check could not be evaluated: forbidden |")
+}
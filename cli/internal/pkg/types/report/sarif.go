@@ -0,0 +1,250 @@
+package report
+
+import (
+	"fmt"
+	"gh_foundations/internal/pkg/types"
+	"sort"
+	"strings"
+)
+
+const (
+	sarifSchema     = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion    = "2.1.0"
+	toolName        = "github-foundations-cli"
+	toolInformation = "https://github.com/canada-ca/fondations-github-foundations"
+	// The base id entity locations are relative to, resolved with originalUriBaseIds
+	githubUriBaseId = "GITHUB"
+)
+
+// The subset of the SARIF 2.1.0 object model used to report check violations
+// (https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html)
+type SarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+type SarifRun struct {
+	Tool               SarifTool                        `json:"tool"`
+	OriginalUriBaseIds map[string]SarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []SarifResult                    `json:"results"`
+}
+
+type SarifTool struct {
+	Driver SarifDriver `json:"driver"`
+}
+
+type SarifDriver struct {
+	Name           string      `json:"name"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []SarifRule `json:"rules"`
+}
+
+type SarifRule struct {
+	Id                   string                 `json:"id"`
+	Name                 string                 `json:"name,omitempty"`
+	ShortDescription     *SarifMessage          `json:"shortDescription,omitempty"`
+	HelpUri              string                 `json:"helpUri,omitempty"`
+	Help                 *SarifMessage          `json:"help,omitempty"`
+	DefaultConfiguration SarifRuleConfiguration `json:"defaultConfiguration"`
+	Properties           SarifRuleProperties    `json:"properties"`
+}
+
+type SarifRuleConfiguration struct {
+	Level string `json:"level"`
+}
+
+type SarifRuleProperties struct {
+	Tags []string `json:"tags,omitempty"`
+	// Used by GitHub code scanning to rank security alerts
+	SecuritySeverity string `json:"security-severity,omitempty"`
+}
+
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+type SarifResult struct {
+	RuleId              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             SarifMessage      `json:"message"`
+	Locations           []SarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type SarifLocation struct {
+	PhysicalLocation SarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []SarifLogicalLocation `json:"logicalLocations"`
+}
+
+type SarifPhysicalLocation struct {
+	ArtifactLocation SarifArtifactLocation `json:"artifactLocation"`
+}
+
+type SarifArtifactLocation struct {
+	Uri       string `json:"uri"`
+	UriBaseId string `json:"uriBaseId,omitempty"`
+}
+
+type SarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// NewSarifLog converts the violations found by the checks into SARIF results, one
+// per violated check and entity, located at the organization, repository or team.
+func NewSarifLog(reports []types.CheckReport, opts Options) SarifLog {
+	run := SarifRun{
+		Tool: SarifTool{Driver: SarifDriver{
+			Name:           toolName,
+			InformationUri: toolInformation,
+			Rules:          []SarifRule{},
+		}},
+		Results: []SarifResult{},
+	}
+	if opts.WebURL != "" {
+		run.OriginalUriBaseIds = map[string]SarifArtifactLocation{githubUriBaseId: {Uri: opts.WebURL}}
+	}
+
+	definitions := make(map[string]types.CheckDefinition)
+	for _, def := range opts.Definitions {
+		definitions[def.EntityType+"/"+def.Id] = def
+	}
+	// Checks of different entities can share an id, their rules are kept apart by
+	// prefixing the id with the entity type
+	ruleIndexes := make(map[string]int)
+
+	for _, report := range reports {
+		path := entityPath(report, opts.Organization)
+		for _, checkErr := range report.Errors {
+			for _, checkId := range sortedKeys(checkErr.Violations) {
+				key := report.EntityType + "/" + checkId
+				def, ok := definitions[key]
+				if !ok {
+					def = types.CheckDefinition{Id: checkId, Type: checkErr.Check, EntityType: report.EntityType}
+				}
+				index, ok := ruleIndexes[key]
+				if !ok {
+					index = len(run.Tool.Driver.Rules)
+					ruleIndexes[key] = index
+					run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, newSarifRule(def))
+				}
+
+				run.Results = append(run.Results, SarifResult{
+					RuleId:    key,
+					RuleIndex: index,
+					Level:     sarifLevel(def.Severity),
+					Message:   SarifMessage{Text: fmt.Sprintf("%s %s: %s", report.EntityType, report.EntityId, checkErr.Violations[checkId])},
+					Locations: []SarifLocation{newSarifLocation(report, path, opts.WebURL != "")},
+					PartialFingerprints: map[string]string{
+						"checkViolation/v1": fmt.Sprintf("%s/%s/%s", report.EntityType, report.EntityId, checkId),
+					},
+				})
+			}
+		}
+	}
+
+	return SarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []SarifRun{run},
+	}
+}
+
+func newSarifRule(def types.CheckDefinition) SarifRule {
+	rule := SarifRule{
+		Id:                   def.EntityType + "/" + def.Id,
+		Name:                 def.Id,
+		DefaultConfiguration: SarifRuleConfiguration{Level: sarifLevel(def.Severity)},
+		Properties: SarifRuleProperties{
+			Tags:             []string{"security", string(def.Type)},
+			SecuritySeverity: securitySeverity(def.Severity),
+		},
+	}
+	if def.Title != "" {
+		rule.ShortDescription = &SarifMessage{Text: def.Title}
+	}
+	if def.Remediation != "" {
+		rule.Help = &SarifMessage{Text: def.Remediation}
+	}
+	for _, g := range def.Guardrails {
+		rule.Properties.Tags = append(rule.Properties.Tags, "guardrail-"+string(g))
+	}
+	if len(def.Guardrails) > 0 {
+		rule.HelpUri = def.Guardrails[0].DocumentURL()
+	}
+	return rule
+}
+
+func newSarifLocation(report types.CheckReport, path string, relative bool) SarifLocation {
+	artifact := SarifArtifactLocation{Uri: path}
+	if relative {
+		artifact.UriBaseId = githubUriBaseId
+	}
+	return SarifLocation{
+		PhysicalLocation: SarifPhysicalLocation{ArtifactLocation: artifact},
+		LogicalLocations: []SarifLogicalLocation{{
+			Name:               report.EntityId,
+			FullyQualifiedName: report.EntityId,
+			Kind:               report.EntityType,
+		}},
+	}
+}
+
+// The path of the entity's page relative to the web URL: orgs/<org> for organizations,
+// <org>/<repo> for repositories and orgs/<org>/teams/<slug> for teams. Repository ids
+// are bare names, qualified with the organization.
+func entityPath(report types.CheckReport, organization string) string {
+	switch report.EntityType {
+	case "github_organization":
+		return "orgs/" + report.EntityId
+	case "github_repository":
+		if organization != "" {
+			return organization + "/" + report.EntityId
+		}
+	case "github_team":
+		if org, slug, found := strings.Cut(report.EntityId, "/"); found {
+			return fmt.Sprintf("orgs/%s/teams/%s", org, slug)
+		}
+	}
+	return report.EntityId
+}
+
+// Map check severities to the SARIF levels shown by code scanning
+func sarifLevel(severity types.Severity) string {
+	switch severity {
+	case types.SeverityCritical, types.SeverityHigh:
+		return "error"
+	case types.SeverityLow:
+		return "note"
+	default:
+		return "warning"
+	}
+}
+
+// Map check severities to the CVSS-like scores GitHub uses to rank security alerts
+func securitySeverity(severity types.Severity) string {
+	switch severity {
+	case types.SeverityCritical:
+		return "9.5"
+	case types.SeverityHigh:
+		return "8.0"
+	case types.SeverityMedium:
+		return "5.5"
+	case types.SeverityLow:
+		return "2.0"
+	default:
+		return ""
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
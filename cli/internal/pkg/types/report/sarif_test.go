@@ -0,0 +1,124 @@
+package report
+
+import (
+	"gh_foundations/internal/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDefinitions = []types.CheckDefinition{
+	{
+		Id:          "secret_scanning",
+		Type:        types.GoCGuardrails,
+		Title:       "Secret scanning is enabled",
+		EntityType:  "github_repository",
+		Severity:    types.SeverityHigh,
+		Guardrails:  []types.Guardrail{types.DataProtection, types.CyberDefenseServices},
+		Controls:    []string{"SC-12", "IA-5(7)"},
+		Remediation: "Enable secret scanning.",
+	},
+	{
+		Id:         "delete_branch_on_merge",
+		Type:       types.GoCGuardrails,
+		EntityType: "github_repository",
+		Severity:   types.SeverityLow,
+		Guardrails: []types.Guardrail{types.CyberDefenseServices},
+	},
+}
+
+var testReports = []types.CheckReport{
+	{
+		EntityType: "github_repository",
+		EntityId:   "octo-repo",
+		Checks:     map[string]types.CheckResult{"secret_scanning": types.Failed, "delete_branch_on_merge": types.Failed},
+		Errors: []types.CheckError{{
+			Check: types.GoCGuardrails,
+			Violations: map[string]string{
+				"secret_scanning":        "secret_scanning is not enabled. Expected it to be enabled",
+				"delete_branch_on_merge": "delete_branch_on_merge is not enabled. Expected it to be enabled",
+			},
+		}},
+	},
+	{
+		EntityType: "github_repository",
+		EntityId:   "other-repo",
+		Checks:     map[string]types.CheckResult{"secret_scanning": types.Failed, "delete_branch_on_merge": types.Passed},
+		Errors: []types.CheckError{{
+			Check:      types.GoCGuardrails,
+			Violations: map[string]string{"secret_scanning": "secret_scanning is not enabled. Expected it to be enabled"},
+		}},
+	},
+}
+
+func TestNewSarifLog(t *testing.T) {
+	log := NewSarifLog(testReports, Options{Definitions: testDefinitions, WebURL: "https://github.com/", Organization: "octo-org"})
+
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, "https://github.com/", run.OriginalUriBaseIds["GITHUB"].Uri)
+
+	require.Len(t, run.Tool.Driver.Rules, 2)
+	rule := run.Tool.Driver.Rules[1]
+	assert.Equal(t, "github_repository/secret_scanning", rule.Id)
+	assert.Equal(t, "secret_scanning", rule.Name)
+	assert.Equal(t, "error", rule.DefaultConfiguration.Level)
+	assert.Equal(t, "https://github.com/canada-ca/fondations-github-foundations/blob/main/guardrails/EN/05_Data-Protection.md", rule.HelpUri)
+	assert.Equal(t, "Enable secret scanning.", rule.Help.Text)
+	assert.Equal(t, []string{"security", "GoCGuardrails", "guardrail-05", "guardrail-07"}, rule.Properties.Tags)
+	assert.Equal(t, "8.0", rule.Properties.SecuritySeverity)
+
+	require.Len(t, run.Results, 3)
+	assert.Equal(t, "github_repository/delete_branch_on_merge", run.Results[0].RuleId)
+	assert.Equal(t, "note", run.Results[0].Level)
+	result := run.Results[2]
+	assert.Equal(t, "github_repository/secret_scanning", result.RuleId)
+	assert.Equal(t, 1, result.RuleIndex)
+	assert.Equal(t, "github_repository other-repo: secret_scanning is not enabled. Expected it to be enabled", result.Message.Text)
+	require.Len(t, result.Locations, 1)
+	assert.Equal(t, SarifArtifactLocation{Uri: "octo-org/other-repo", UriBaseId: "GITHUB"}, result.Locations[0].PhysicalLocation.ArtifactLocation)
+	assert.Equal(t, "github_repository", result.Locations[0].LogicalLocations[0].Kind)
+}
+
+func TestNewSarifLogUnknownCheck(t *testing.T) {
+	log := NewSarifLog(testReports[1:], Options{})
+
+	run := log.Runs[0]
+	require.Len(t, run.Tool.Driver.Rules, 1)
+	assert.Equal(t, "warning", run.Tool.Driver.Rules[0].DefaultConfiguration.Level)
+	assert.Empty(t, run.OriginalUriBaseIds)
+	assert.Equal(t, SarifArtifactLocation{Uri: "other-repo"}, run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation)
+}
+
+func TestNewSarifLogEntityLocations(t *testing.T) {
+	violation := func(entityType string, entityId string) types.CheckReport {
+		return types.CheckReport{
+			EntityType: entityType,
+			EntityId:   entityId,
+			Errors:     []types.CheckError{{Check: types.GoCGuardrails, Violations: map[string]string{"admins": "too many admins"}}},
+		}
+	}
+	reports := []types.CheckReport{
+		violation("github_organization", "octo-org"),
+		violation("github_repository", "octo-repo"),
+		violation("github_team", "octo-org/web"),
+		violation("github_repository", "other-repo"),
+	}
+
+	log := NewSarifLog(reports, Options{WebURL: "https://github.com/", Organization: "octo-org"})
+
+	run := log.Runs[0]
+	var uris []string
+	for _, result := range run.Results {
+		uris = append(uris, result.Locations[0].PhysicalLocation.ArtifactLocation.Uri)
+	}
+	assert.Equal(t, []string{"orgs/octo-org", "octo-org/octo-repo", "orgs/octo-org/teams/web", "octo-org/other-repo"}, uris)
+
+	// A rule per entity type, shared by the entities of the type
+	require.Len(t, run.Tool.Driver.Rules, 3)
+	assert.Equal(t, []int{0, 1, 2, 1}, []int{run.Results[0].RuleIndex, run.Results[1].RuleIndex, run.Results[2].RuleIndex, run.Results[3].RuleIndex})
+	assert.Equal(t, "github_organization/admins", run.Tool.Driver.Rules[0].Id)
+	assert.Equal(t, "github_team/admins", run.Results[2].RuleId)
+}
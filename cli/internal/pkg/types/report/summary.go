@@ -0,0 +1,131 @@
+package report
+
+import (
+	"gh_foundations/internal/pkg/types"
+	"sort"
+)
+
+// Summary aggregates check reports for the human-readable formats
+type Summary struct {
+	Passed     int
+	Failed     int
+	Errored    int
+	Guardrails []GuardrailSummary
+	Entities   []EntitySummary
+	Violations []Violation
+	// The checks that could not be evaluated, with the reason as message
+	Errors []Violation
+}
+
+// GuardrailSummary counts the check results of the checks that map to a guardrail
+type GuardrailSummary struct {
+	Guardrail types.Guardrail
+	Passed    int
+	Failed    int
+}
+
+// EntitySummary counts the check results of an organization or repository
+type EntitySummary struct {
+	EntityType string
+	EntityId   string
+	Passed     int
+	Failed     int
+}
+
+type Violation struct {
+	EntityType string
+	EntityId   string
+	CheckId    string
+	Severity   types.Severity
+	Guardrails []types.Guardrail
+	Message    string
+}
+
+func NewSummary(reports []types.CheckReport, definitions []types.CheckDefinition) Summary {
+	var summary Summary
+	byId := make(map[string]types.CheckDefinition)
+	for _, def := range definitions {
+		byId[def.EntityType+"/"+def.Id] = def
+	}
+	guardrails := make(map[types.Guardrail]*GuardrailSummary)
+
+	for _, report := range reports {
+		entity := EntitySummary{EntityType: report.EntityType, EntityId: report.EntityId}
+		for checkId, result := range report.Checks {
+			if result == types.Errored {
+				summary.Errored++
+			}
+			if result != types.Passed && result != types.Failed {
+				continue
+			}
+			def := byId[report.EntityType+"/"+checkId]
+			for _, g := range def.Guardrails {
+				if _, ok := guardrails[g]; !ok {
+					guardrails[g] = &GuardrailSummary{Guardrail: g}
+				}
+				count(result, &guardrails[g].Passed, &guardrails[g].Failed)
+			}
+			count(result, &entity.Passed, &entity.Failed)
+		}
+		summary.Passed += entity.Passed
+		summary.Failed += entity.Failed
+		summary.Entities = append(summary.Entities, entity)
+
+		for _, checkErr := range report.Errors {
+			for _, checkId := range sortedKeys(checkErr.Violations) {
+				def := byId[report.EntityType+"/"+checkId]
+				summary.Violations = append(summary.Violations, Violation{
+					EntityType: report.EntityType,
+					EntityId:   report.EntityId,
+					CheckId:    checkId,
+					Severity:   def.Severity,
+					Guardrails: def.Guardrails,
+					Message:    checkErr.Violations[checkId],
+				})
+			}
+			for _, checkId := range sortedKeys(checkErr.Errored) {
+				def := byId[report.EntityType+"/"+checkId]
+				summary.Errors = append(summary.Errors, Violation{
+					EntityType: report.EntityType,
+					EntityId:   report.EntityId,
+					CheckId:    checkId,
+					Severity:   def.Severity,
+					Guardrails: def.Guardrails,
+					Message:    checkErr.Errored[checkId],
+				})
+			}
+		}
+	}
+
+	for _, g := range guardrails {
+		summary.Guardrails = append(summary.Guardrails, *g)
+	}
+	sort.Slice(summary.Guardrails, func(i, j int) bool {
+		return summary.Guardrails[i].Guardrail < summary.Guardrails[j].Guardrail
+	})
+	// The most severe violations come first
+	sort.SliceStable(summary.Violations, func(i, j int) bool {
+		return summary.Violations[i].Severity.Rank() > summary.Violations[j].Severity.Rank()
+	})
+	return summary
+}
+
+// CountViolations counts the violations of checks at or above the severity, every
+// violation when no severity is given
+func (s Summary) CountViolations(severity types.Severity) int {
+	n := 0
+	for _, v := range s.Violations {
+		if severity == "" || v.Severity.Rank() >= severity.Rank() {
+			n++
+		}
+	}
+	return n
+}
+
+func count(result types.CheckResult, passed *int, failed *int) {
+	if result == types.Passed {
+		*passed++
+	} else {
+		*failed++
+	}
+}
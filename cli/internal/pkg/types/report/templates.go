@@ -0,0 +1,70 @@
+package report
+
+import (
+	"embed"
+	"fmt"
+	"gh_foundations/internal/pkg/types"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var templateFuncs = map[string]any{
+	"guardrails": joinGuardrails,
+	"markdown":   escapeMarkdown,
+}
+
+var markdownTemplate = template.Must(template.New("report.md.tmpl").Funcs(templateFuncs).ParseFS(templates, "templates/report.md.tmpl"))
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("report.html.tmpl").Funcs(templateFuncs).ParseFS(templates, "templates/report.html.tmpl"))
+
+func writeTable(w io.Writer, summary Summary) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d checks passed, %d checks failed", summary.Passed, summary.Failed)
+	if summary.Errored > 0 {
+		fmt.Fprintf(tw, ", %d checks could not be evaluated", summary.Errored)
+	}
+	fmt.Fprint(tw, "\n\n")
+
+	fmt.Fprintln(tw, "GUARDRAIL\tPASSED\tFAILED")
+	for _, g := range summary.Guardrails {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", g.Guardrail, g.Passed, g.Failed)
+	}
+
+	fmt.Fprintln(tw, "\nENTITY\tTYPE\tPASSED\tFAILED")
+	for _, e := range summary.Entities {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", e.EntityId, e.EntityType, e.Passed, e.Failed)
+	}
+
+	if len(summary.Violations) > 0 {
+		fmt.Fprintln(tw, "\nENTITY\tCHECK\tSEVERITY\tGUARDRAILS\tVIOLATION")
+		for _, v := range summary.Violations {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", v.EntityId, v.CheckId, v.Severity, joinGuardrails(v.Guardrails), v.Message)
+		}
+	}
+
+	if len(summary.Errors) > 0 {
+		fmt.Fprintln(tw, "\nENTITY\tCHECK\tERROR")
+		for _, e := range summary.Errors {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", e.EntityId, e.CheckId, e.Message)
+		}
+	}
+	return tw.Flush()
+}
+
+func joinGuardrails(guardrails []types.Guardrail) string {
+	ids := make([]string, len(guardrails))
+	for i, g := range guardrails {
+		ids[i] = string(g)
+	}
+	return strings.Join(ids, ",")
+}
+
+// Escape the characters that would break a Markdown table cell
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
@@ -2,6 +2,8 @@ package status
 
 import (
 	githubfoundations "gh_foundations/internal/pkg/types/github_foundations"
+
+	"github.com/hashicorp/hcl/v2"
 )
 
 
@@ -25,11 +27,41 @@ type Repository struct {
 	ProtectedBranches 			[]string	`mapstructure:"protected_branches"`
 	RequiresWebCommitSignOff 	bool 		`mapstructure:"requires_web_commit_signing"`
 	Topics 						[]string	`mapstructure:"topics"`
+	RepositoryTeamPermissionsOverride	map[string]string	`mapstructure:"repository_team_permissions_override"`
+	UserPermissions 			map[string]string	`mapstructure:"user_permissions"`
+	OrganizationActionSecrets 	[]string	`mapstructure:"organization_action_secrets"`
+	OrganizationCodespaceSecrets	[]string	`mapstructure:"organization_codespace_secrets"`
+	OrganizationDependabotSecrets	[]string	`mapstructure:"organization_dependabot_secrets"`
+	ActionSecrets 				map[string]string	`mapstructure:"action_secrets"`
+	CodespaceSecrets 			map[string]string	`mapstructure:"codespace_secrets"`
+	DependabotSecrets 			map[string]string	`mapstructure:"dependabot_secrets"`
+	Environments 				map[string]Environment	`mapstructure:"environments"`
+	TemplateRepository 			*TemplateRepository	`mapstructure:"template_repository"`
+	LicenseTemplate 			string 		`mapstructure:"license_template"`
+	// Where the repository is defined in its repository set
+	Range 						hcl.Range	`mapstructure:"-"`
+	// The names of the inputs the repository set gives the repository
+	Inputs 						[]string	`mapstructure:"-"`
+}
+
+type Environment struct {
+	ActionSecrets 		map[string]string	`mapstructure:"action_secrets"`
+}
+
+type TemplateRepository struct {
+	Owner 				string	`mapstructure:"owner"`
+	Repository 			string	`mapstructure:"repository"`
+	IncludeAllBranches 	bool	`mapstructure:"include_all_branches"`
 }
 
 
 type OrgProjectSet struct {
 	RepositorySets 		map[string]githubfoundations.RepositorySetInput
+	// The path of the repositories/terragrunt.hcl file each project's repository set was read from
+	RepositorySetPaths 	map[string]string
+	TeamSets 			map[string]githubfoundations.TeamSetInput
+	// The path of the teams/terragrunt.hcl file each project's team set was read from
+	TeamSetPaths 		map[string]string
 }
 
 type OrgSet struct {
@@ -45,6 +77,7 @@ func (org OrgSet) WithGHASEnabled() OrgSet {
 	for orgName, projects := range org.OrgProjectSets {
 		ptrOrgProjectSet := new(OrgProjectSet)
 		ptrOrgProjectSet.RepositorySets = make(map[string]githubfoundations.RepositorySetInput)
+		ptrOrgProjectSet.RepositorySetPaths = projects.RepositorySetPaths
 		reposWithGHAS.OrgProjectSets[orgName] = *ptrOrgProjectSet
 
 		for projectName, repoSet := range projects.RepositorySets {
@@ -67,8 +100,26 @@ func (org OrgSet) WithGHASEnabled() OrgSet {
 
 // Given a repository struct returned by the HCL parser, return a githubfoundations.RepositoryInput
 func (repo *Repository) GetRepositoryInput() githubfoundations.RepositoryInput {
+	var environments map[string]githubfoundations.EnvironmentInputs
+	if repo.Environments != nil {
+		environments = make(map[string]githubfoundations.EnvironmentInputs, len(repo.Environments))
+		for name, environment := range repo.Environments {
+			environments[name] = githubfoundations.EnvironmentInputs{ActionSecrets: environment.ActionSecrets}
+		}
+	}
+
+	var templateRepository *githubfoundations.TemplateRepositoryInputs
+	if repo.TemplateRepository != nil {
+		templateRepository = &githubfoundations.TemplateRepositoryInputs{
+			Owner: repo.TemplateRepository.Owner,
+			Repository: repo.TemplateRepository.Repository,
+			IncludeAllBranches: repo.TemplateRepository.IncludeAllBranches,
+		}
+	}
+
 	return githubfoundations.RepositoryInput{
 		Name: repo.Name,
+		AllowUpdateBranch: repo.AllowUpdateBranch,
 		AdvanceSecurity: repo.AdvanceSecurity,
 		AllowAutoMerge: repo.AllowAutoMerge,
 		DefaultBranch: repo.DefaultBranch,
@@ -80,5 +131,17 @@ func (repo *Repository) GetRepositoryInput() githubfoundations.RepositoryInput {
 		ProtectedBranches: repo.ProtectedBranches,
 		RequiresWebCommitSignOff: repo.RequiresWebCommitSignOff,
 		Topics: repo.Topics,
+		RepositoryTeamPermissionsOverride: repo.RepositoryTeamPermissionsOverride,
+		UserPermissions: repo.UserPermissions,
+		OrganizationActionSecrets: repo.OrganizationActionSecrets,
+		OrganizationCodespaceSecrets: repo.OrganizationCodespaceSecrets,
+		OrganizationDependabotSecrets: repo.OrganizationDependabotSecrets,
+		ActionSecrets: repo.ActionSecrets,
+		CodespaceSecrets: repo.CodespaceSecrets,
+		DependabotSecrets: repo.DependabotSecrets,
+		Environments: environments,
+		TemplateRepository: templateRepository,
+		LicenseTemplate: repo.LicenseTemplate,
+		SetInputs: repo.Inputs,
 	}
 }
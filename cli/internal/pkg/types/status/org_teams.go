@@ -0,0 +1,40 @@
+package status
+
+import (
+	githubfoundations "gh_foundations/internal/pkg/types/github_foundations"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+type TeamInputs struct {
+	Teams map[string]Team `mapstructure:"teams"`
+}
+
+type Team struct {
+	Name        string   `mapstructure:",label"`
+	Description string   `mapstructure:"description"`
+	Privacy     string   `mapstructure:"privacy"`
+	Maintainers []string `mapstructure:"maintainers"`
+	Members     []string `mapstructure:"members"`
+	// gen team_set writes parent_id, the team set documentation uses parent_team_id
+	ParentId     string `mapstructure:"parent_id"`
+	ParentTeamId string `mapstructure:"parent_team_id"`
+	// Where the team is defined in its team set
+	Range hcl.Range `mapstructure:"-"`
+}
+
+// Given a team struct returned by the HCL parser, return a githubfoundations.TeamInput
+func (team *Team) GetTeamInput() githubfoundations.TeamInput {
+	parentId := team.ParentId
+	if parentId == "" {
+		parentId = team.ParentTeamId
+	}
+	return githubfoundations.TeamInput{
+		Name:        team.Name,
+		Description: team.Description,
+		Privacy:     team.Privacy,
+		Maintainers: team.Maintainers,
+		Members:     team.Members,
+		ParentId:    parentId,
+	}
+}
@@ -0,0 +1,447 @@
+package terragrunt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// The file find_in_parent_folders looks for by default
+const defaultConfigName = "terragrunt.hcl"
+
+// Config is a Terragrunt configuration file evaluated with hcl/v2: its locals, its
+// include blocks and the inputs, along with a safe subset of the Terragrunt functions.
+// Values only known when Terragrunt runs, such as dependency outputs or environment
+// variables that are not set, are unknown.
+type Config struct {
+	Path   string
+	Locals map[string]cty.Value
+	// The inputs of the configuration, merged over the inputs of the included configurations
+	Inputs map[string]Input
+}
+
+// Input is the value of an input, with the source range of the expression defining it
+type Input struct {
+	Value cty.Value
+	Range hcl.Range
+	// The ranges of the items of an input written as an object, e.g. of every repository
+	// of a repository set, by key
+	Items map[string]hcl.Range
+}
+
+// ParseConfig reads and evaluates a Terragrunt configuration file
+func ParseConfig(path string) (*Config, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(absPath, filepath.Dir(absPath), true)
+}
+
+// Included configurations are evaluated in the directory of the including configuration,
+// like Terragrunt does, and cannot include other configurations.
+func parseConfig(path string, terragruntDir string, allowInclude bool) (*Config, error) {
+	content, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, &ConfigError{Path: path, Err: err}
+	}
+	file, diags := hclsyntax.ParseConfig(content, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diagnosticsError(path, diags)
+	}
+	body := file.Body.(*hclsyntax.Body)
+
+	config := &Config{Path: path, Locals: make(map[string]cty.Value), Inputs: make(map[string]Input)}
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{},
+		Functions: functions(terragruntDir),
+	}
+
+	var localBlocks, includeBlocks []*hclsyntax.Block
+	dependencies := make(map[string]cty.Value)
+	for _, block := range body.Blocks {
+		switch block.Type {
+		case "locals":
+			localBlocks = append(localBlocks, block)
+		case "include":
+			includeBlocks = append(includeBlocks, block)
+		case "dependency":
+			// The outputs of dependencies are only known once they are applied
+			if len(block.Labels) == 1 {
+				dependencies[block.Labels[0]] = cty.DynamicVal
+			}
+		}
+	}
+
+	if err := evaluateLocals(path, localBlocks, ctx, config.Locals); err != nil {
+		return nil, err
+	}
+
+	includes := make(map[string]cty.Value)
+	for _, block := range includeBlocks {
+		if !allowInclude {
+			return nil, rangeError(block.DefRange(), "included configurations cannot include other configurations")
+		}
+		included, exposed, err := parseInclude(block, ctx, path, terragruntDir)
+		if err != nil {
+			return nil, err
+		}
+		for key, input := range included.Inputs {
+			config.Inputs[key] = input
+		}
+		if exposed {
+			includes[block.Labels[0]] = included.value()
+		}
+	}
+	ctx.Variables["include"] = cty.ObjectVal(includes)
+	ctx.Variables["dependency"] = cty.ObjectVal(dependencies)
+
+	if attr, ok := body.Attributes["inputs"]; ok {
+		inputs, diags := evaluate(attr.Expr, ctx)
+		if diags.HasErrors() {
+			return nil, diagnosticsError(path, diags)
+		}
+		if !inputs.IsKnown() || inputs.IsNull() || !inputs.Type().IsObjectType() {
+			return nil, rangeError(attr.Expr.Range(), "inputs must be an object")
+		}
+		items := itemRanges(attr.Expr)
+		for key, value := range inputs.AsValueMap() {
+			input := Input{Value: value, Range: attr.Expr.Range()}
+			if r, ok := items[key]; ok {
+				input.Range = r
+			}
+			if item := findItem(attr.Expr, key); item != nil {
+				input.Items = itemRanges(item.ValueExpr)
+			}
+			// Like Terragrunt, inputs are merged shallowly over the included ones
+			config.Inputs[key] = input
+		}
+	}
+	return config, nil
+}
+
+// The attributes an include block exposes with include.<name>
+func (c *Config) value() cty.Value {
+	inputs := make(map[string]cty.Value, len(c.Inputs))
+	for key, input := range c.Inputs {
+		inputs[key] = input.Value
+	}
+	return cty.ObjectVal(map[string]cty.Value{
+		"locals": cty.ObjectVal(c.Locals),
+		"inputs": cty.ObjectVal(inputs),
+	})
+}
+
+func parseInclude(block *hclsyntax.Block, ctx *hcl.EvalContext, path string, terragruntDir string) (*Config, bool, error) {
+	pathAttr, ok := block.Body.Attributes["path"]
+	if !ok {
+		return nil, false, rangeError(block.DefRange(), "include blocks require a path")
+	}
+	value, diags := pathAttr.Expr.Value(ctx)
+	if diags.HasErrors() {
+		return nil, false, diagnosticsError(path, diags)
+	}
+	if !value.IsKnown() || value.IsNull() || value.Type() != cty.String {
+		return nil, false, rangeError(pathAttr.Expr.Range(), "the path of the include must be a known string")
+	}
+	includePath := value.AsString()
+	if !filepath.IsAbs(includePath) {
+		includePath = filepath.Join(filepath.Dir(path), includePath)
+	}
+
+	exposed := false
+	if exposeAttr, ok := block.Body.Attributes["expose"]; ok {
+		expose, diags := exposeAttr.Expr.Value(ctx)
+		if diags.HasErrors() {
+			return nil, false, diagnosticsError(path, diags)
+		}
+		if expose, err := convert.Convert(expose, cty.Bool); err != nil || !expose.IsKnown() || expose.IsNull() {
+			return nil, false, rangeError(exposeAttr.Expr.Range(), "expose must be a bool")
+		} else {
+			exposed = expose.True() && len(block.Labels) == 1
+		}
+	}
+
+	included, err := parseConfig(includePath, terragruntDir, false)
+	return included, exposed, err
+}
+
+// Locals can refer to each other in any order, so they are evaluated once the locals they
+// refer to are known
+func evaluateLocals(path string, blocks []*hclsyntax.Block, ctx *hcl.EvalContext, locals map[string]cty.Value) error {
+	pending := make(map[string]*hclsyntax.Attribute)
+	for _, block := range blocks {
+		for name, attr := range block.Body.Attributes {
+			if _, ok := pending[name]; ok {
+				return rangeError(attr.NameRange, "local.%s is defined more than once", name)
+			}
+			pending[name] = attr
+		}
+	}
+
+	ctx.Variables["local"] = cty.EmptyObjectVal
+	for len(pending) > 0 {
+		progress := false
+		for name, attr := range pending {
+			if !referencesKnown(attr.Expr, locals) {
+				continue
+			}
+			value, diags := evaluate(attr.Expr, ctx)
+			if diags.HasErrors() {
+				return diagnosticsError(path, diags)
+			}
+			locals[name] = value
+			ctx.Variables["local"] = cty.ObjectVal(locals)
+			delete(pending, name)
+			progress = true
+		}
+		if !progress {
+			var errs ConfigErrors
+			for name, attr := range pending {
+				errs = append(errs, rangeError(attr.Expr.Range(), "local.%s refers to a local that is not defined or refers back to it", name))
+			}
+			errs.sort()
+			return errs
+		}
+	}
+	return nil
+}
+
+func referencesKnown(expr hclsyntax.Expression, locals map[string]cty.Value) bool {
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "local" || len(traversal) < 2 {
+			continue
+		}
+		if attr, ok := traversal[1].(hcl.TraverseAttr); ok {
+			if _, known := locals[attr.Name]; !known {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// evaluate evaluates objects item by item, so the items whose key is only known when
+// Terragrunt runs, e.g. a key set from a dependency output, are left out instead of
+// making the whole object unknown
+func evaluate(expr hclsyntax.Expression, ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	obj, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return expr.Value(ctx)
+	}
+
+	var diags hcl.Diagnostics
+	attrs := make(map[string]cty.Value)
+	for _, item := range obj.Items {
+		key, keyDiags := item.KeyExpr.Value(ctx)
+		value, valueDiags := evaluate(item.ValueExpr, ctx)
+		diags = append(append(diags, keyDiags...), valueDiags...)
+		if keyDiags.HasErrors() || valueDiags.HasErrors() || !key.IsKnown() {
+			continue
+		}
+		key, err := convert.Convert(key, cty.String)
+		if err != nil || key.IsNull() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid object key",
+				Detail:   "Object keys must be strings.",
+				Subject:  item.KeyExpr.Range().Ptr(),
+			})
+			continue
+		}
+		attrs[key.AsString()] = value
+	}
+	return cty.ObjectVal(attrs), diags
+}
+
+// The item of an object expression with a literal key
+func findItem(expr hclsyntax.Expression, key string) *hclsyntax.ObjectConsItem {
+	obj, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return nil
+	}
+	for i, item := range obj.Items {
+		k, diags := item.KeyExpr.Value(nil)
+		if !diags.HasErrors() && k.IsKnown() && k.Type() == cty.String && k.AsString() == key {
+			return &obj.Items[i]
+		}
+	}
+	return nil
+}
+
+// The ranges of the items of an object expression with literal keys, from the key to the
+// end of the value
+func itemRanges(expr hclsyntax.Expression) map[string]hcl.Range {
+	ranges := make(map[string]hcl.Range)
+	obj, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return ranges
+	}
+	for _, item := range obj.Items {
+		k, diags := item.KeyExpr.Value(nil)
+		if !diags.HasErrors() && k.IsKnown() && k.Type() == cty.String {
+			ranges[k.AsString()] = hcl.RangeBetween(item.KeyExpr.Range(), item.ValueExpr.Range())
+		}
+	}
+	return ranges
+}
+
+// The Terragrunt functions that only read the configuration's environment, along with a
+// few pure functions of the Terraform language
+func functions(terragruntDir string) map[string]function.Function {
+	return map[string]function.Function{
+		"find_in_parent_folders": findInParentFolders(terragruntDir),
+		"get_repo_root":          getRepoRoot(terragruntDir),
+		"get_terragrunt_dir":     constant(terragruntDir),
+		"get_env":                getEnv,
+		"basename":               pathFunction(filepath.Base),
+		"dirname":                pathFunction(filepath.Dir),
+		"concat":                 stdlib.ConcatFunc,
+		"format":                 stdlib.FormatFunc,
+		"join":                   stdlib.JoinFunc,
+		"lower":                  stdlib.LowerFunc,
+		"merge":                  stdlib.MergeFunc,
+		"upper":                  stdlib.UpperFunc,
+	}
+}
+
+func constant(value string) function.Function {
+	return function.New(&function.Spec{
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			return cty.StringVal(value), nil
+		},
+	})
+}
+
+func pathFunction(fn func(string) string) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{{Name: "path", Type: cty.String}},
+		Type:   function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			return cty.StringVal(fn(args[0].AsString())), nil
+		},
+	})
+}
+
+// find_in_parent_folders([name], [fallback]) returns the path of the first file with the
+// name found in the parent folders of the configuration
+func findInParentFolders(terragruntDir string) function.Function {
+	return function.New(&function.Spec{
+		VarParam: &function.Parameter{Name: "args", Type: cty.String},
+		Type:     function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			if len(args) > 2 {
+				return cty.NilVal, errors.New("find_in_parent_folders takes a file name and a fallback value at most")
+			}
+			name := defaultConfigName
+			if len(args) > 0 {
+				name = args[0].AsString()
+			}
+			for dir := filepath.Dir(terragruntDir); ; dir = filepath.Dir(dir) {
+				candidate := filepath.Join(dir, name)
+				if _, err := fs.Stat(candidate); err == nil {
+					return cty.StringVal(candidate), nil
+				}
+				if dir == filepath.Dir(dir) {
+					break
+				}
+			}
+			if len(args) == 2 {
+				return args[1], nil
+			}
+			return cty.NilVal, fmt.Errorf("%s not found in the parent folders of %s", name, terragruntDir)
+		},
+	})
+}
+
+// get_repo_root() returns the root of the git repository of the configuration
+func getRepoRoot(terragruntDir string) function.Function {
+	return function.New(&function.Spec{
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			for dir := terragruntDir; ; dir = filepath.Dir(dir) {
+				if _, err := fs.Stat(filepath.Join(dir, ".git")); err == nil {
+					return cty.StringVal(dir), nil
+				}
+				if dir == filepath.Dir(dir) {
+					return cty.NilVal, fmt.Errorf("%s is not in a git repository", terragruntDir)
+				}
+			}
+		},
+	})
+}
+
+// get_env(name, [default]) returns the value of an environment variable. Variables that are
+// not set and have no default are unknown, as they are only known where Terragrunt runs.
+var getEnv = function.New(&function.Spec{
+	VarParam: &function.Parameter{Name: "args", Type: cty.String},
+	Type:     function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		if len(args) == 0 || len(args) > 2 {
+			return cty.NilVal, errors.New("get_env takes the name of a variable and a default value")
+		}
+		if value, ok := os.LookupEnv(args[0].AsString()); ok {
+			return cty.StringVal(value), nil
+		}
+		if len(args) == 2 {
+			return args[1], nil
+		}
+		return cty.UnknownVal(cty.String), nil
+	},
+})
+
+// toGo converts a value to the Go values mapstructure decodes, leaving unknown values out
+func toGo(value cty.Value) any {
+	if !value.IsKnown() || value.IsNull() {
+		return nil
+	}
+	ty := value.Type()
+	switch {
+	case ty == cty.String:
+		return value.AsString()
+	case ty == cty.Bool:
+		return value.True()
+	case ty == cty.Number:
+		f, _ := value.AsBigFloat().Float64()
+		return f
+	case ty.IsObjectType() || ty.IsMapType():
+		m := make(map[string]any)
+		for key, v := range value.AsValueMap() {
+			if v.IsKnown() {
+				m[key] = toGo(v)
+			}
+		}
+		return m
+	case ty.IsListType() || ty.IsTupleType() || ty.IsSetType():
+		l := make([]any, 0, value.LengthInt())
+		for _, v := range value.AsValueSlice() {
+			if v.IsKnown() {
+				l = append(l, toGo(v))
+			}
+		}
+		return l
+	default:
+		return nil
+	}
+}
+
+// The string values of the known locals
+func (c *Config) stringLocals() map[string]string {
+	locals := make(map[string]string)
+	for name, value := range c.Locals {
+		if s, err := convert.Convert(value, cty.String); err == nil && s.IsKnown() && !s.IsNull() {
+			locals[name] = s.AsString()
+		}
+	}
+	return locals
+}
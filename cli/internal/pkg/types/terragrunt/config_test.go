@@ -0,0 +1,455 @@
+package terragrunt
+
+import (
+	githubfoundations "gh_foundations/internal/pkg/types/github_foundations"
+	"gh_foundations/internal/pkg/types/status"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const rootConfig = `locals {
+  tf_state_bucket_name = get_env("GH_FOUNDATIONS_TEST_UNSET")
+}
+
+remote_state {
+  backend = "gcs"
+  config = {
+    bucket = "${local.tf_state_bucket_name}"
+    prefix = "terraform/github-foundations/organizations/${path_relative_to_include()}"
+  }
+}
+`
+
+const providersConfig = `locals {
+  organization_name      = "octo-org"
+  secret_manager_project = get_env("GH_FOUNDATIONS_TEST_UNSET")
+}
+
+generate "github_provider" {
+  path     = "provider.tf"
+  contents = <<EOF
+provider "github" {
+  owner = "${local.organization_name}"
+}
+EOF
+}
+`
+
+const repositorySetConfig = `include "root" {
+  path   = find_in_parent_folders()
+  expose = true
+}
+
+include "providers" {
+  path   = "${get_repo_root()}/providers/${basename(dirname(get_terragrunt_dir()))}/providers.hcl"
+  expose = true
+}
+
+locals {
+  description  = "${local.org} ${local.organization}"
+  org          = "Octo"
+  organization = "Organization"
+}
+
+inputs = {
+  private_repositories = {
+    "app" = {
+      description          = local.description
+      default_branch       = get_env("GH_FOUNDATIONS_TEST_UNSET", "main")
+      homepage             = "https://github.com/${include.providers.locals.organization_name}/app"
+      topics               = ["go", lower("API")]
+      delete_head_on_merge = true
+      repository_team_permissions_override = {
+        "${dependency.teams.outputs.team_slugs["admins"]}" = "admin"
+      }
+    }
+  }
+  public_repositories = {}
+
+  default_repository_team_permissions = {
+    "${dependency.teams.outputs.team_slugs["devs"]}" = "push"
+    maintainers                                      = "maintain"
+  }
+}
+
+dependency "teams" {
+  config_path = "../teams"
+}
+`
+
+const repositorySetPath = "/repo/projects/project/octo-org/repositories/terragrunt.hcl"
+
+type ConfigTestSuite struct {
+	suite.Suite
+}
+
+func TestConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigTestSuite))
+}
+
+func (s *ConfigTestSuite) SetupTest() {
+	fs = afero.NewMemMapFs()
+	require.NoError(s.T(), fs.MkdirAll("/repo/.git", 0755))
+	s.writeFile("/repo/projects/terragrunt.hcl", rootConfig)
+	s.writeFile("/repo/providers/octo-org/providers.hcl", providersConfig)
+	s.writeFile(repositorySetPath, repositorySetConfig)
+}
+
+func (s *ConfigTestSuite) writeFile(path string, content string) {
+	require.NoError(s.T(), afero.WriteFile(fs, path, []byte(content), 0644))
+}
+
+func (s *ConfigTestSuite) TestParseConfig() {
+	config, err := ParseConfig(repositorySetPath)
+	require.NoError(s.T(), err)
+
+	assert.Equal(s.T(), cty.StringVal("Octo Organization"), config.Locals["description"])
+
+	repos := config.Inputs["private_repositories"]
+	assert.Equal(s.T(), 18, repos.Range.Start.Line)
+	assert.Equal(s.T(), 19, repos.Items["app"].Start.Line)
+	assert.Equal(s.T(), 28, repos.Items["app"].End.Line)
+
+	app := repos.Value.GetAttr("app")
+	assert.Equal(s.T(), cty.StringVal("main"), app.GetAttr("default_branch"))
+	assert.Equal(s.T(), cty.StringVal("https://github.com/octo-org/app"), app.GetAttr("homepage"))
+	// Keys only known once the dependencies are applied are left out
+	assert.Equal(s.T(), cty.EmptyObjectVal, app.GetAttr("repository_team_permissions_override"))
+}
+
+func (s *ConfigTestSuite) TestGetInputsFromFile() {
+	hclFile := HCLFile{Path: repositorySetPath}
+	inputs, err := hclFile.GetInputsFromFile()
+	require.NoError(s.T(), err)
+
+	require.Contains(s.T(), inputs.PrivateRepositories, "app")
+	app := inputs.PrivateRepositories["app"]
+	assert.Equal(s.T(), "app", app.Name)
+	assert.Equal(s.T(), "Octo Organization", app.Description)
+	assert.Equal(s.T(), []string{"go", "api"}, app.Topics)
+	assert.True(s.T(), app.DeleteHeadBranchOnMerge)
+	assert.Equal(s.T(), repositorySetPath, app.Range.Filename)
+	assert.Equal(s.T(), 19, app.Range.Start.Line)
+	assert.Empty(s.T(), inputs.PublicRepositories)
+	assert.Equal(s.T(), map[string]string{"maintainers": "maintain"}, inputs.DefaultRepositoryTeamPermissions)
+}
+
+func (s *ConfigTestSuite) TestGetInputsFromFileUnknownInput() {
+	s.writeFile(repositorySetPath, "inputs = {\n  teams = {}\n}\n")
+
+	hclFile := HCLFile{Path: repositorySetPath}
+	_, err := hclFile.GetInputsFromFile()
+	assert.EqualError(s.T(), err, repositorySetPath+":2: unknown input teams")
+}
+
+func (s *ConfigTestSuite) TestGetInputsFromFileAggregatesErrors() {
+	s.writeFile(repositorySetPath, `inputs = {
+  teams = {}
+  private_repositories = {
+    app = {
+      description = "App"
+    }
+    docs = {
+      topics = "docs"
+    }
+  }
+}
+`)
+
+	hclFile := HCLFile{Path: repositorySetPath}
+	inputs, err := hclFile.GetInputsFromFile()
+
+	// The repositories that can be read are still returned
+	assert.Contains(s.T(), inputs.PrivateRepositories, "app")
+	assert.NotContains(s.T(), inputs.PrivateRepositories, "docs")
+
+	var errs ConfigErrors
+	require.ErrorAs(s.T(), err, &errs)
+	require.Len(s.T(), errs, 2)
+	assert.Equal(s.T(), repositorySetPath, errs[0].Path)
+	assert.Equal(s.T(), 2, errs[0].Line)
+	assert.EqualError(s.T(), errs[0].Err, "unknown input teams")
+	assert.Equal(s.T(), 7, errs[1].Line)
+	assert.ErrorContains(s.T(), errs[1].Err, "invalid repository docs")
+}
+
+func (s *ConfigTestSuite) TestGetLocalsMap() {
+	hclFile := HCLFile{Path: "/repo/providers/octo-org/providers.hcl"}
+
+	locals, err := hclFile.GetLocalsMap()
+	require.NoError(s.T(), err)
+	// Environment variables that are not set are unknown, not empty
+	assert.Equal(s.T(), map[string]string{"organization_name": "octo-org"}, locals)
+}
+
+func (s *ConfigTestSuite) TestParseConfigErrors() {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"syntax", "inputs = {", repositorySetPath + ":1: Missing expression"},
+		{"cycle", "locals {\n  a = local.b\n  b = local.a\n}\n", repositorySetPath + ":2: local.a refers to a local that is not defined or refers back to it"},
+		{"undefined local", "locals {\n  a = local.missing\n}\n", "local.a refers to a local that is not defined"},
+		{"missing parent", "include {\n  path = find_in_parent_folders(\"missing.hcl\")\n}\n", "missing.hcl not found in the parent folders"},
+		{"unsupported function", "inputs = {\n  a = run_cmd(\"ls\")\n}\n", "Call to unknown function"},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			s.writeFile(repositorySetPath, test.content)
+			_, err := ParseConfig(repositorySetPath)
+			require.Error(s.T(), err)
+			assert.Contains(s.T(), err.Error(), test.err)
+		})
+	}
+}
+
+func (s *ConfigTestSuite) TestParseConfigNestedInclude() {
+	s.writeFile("/repo/projects/terragrunt.hcl", "include {\n  path = \"other.hcl\"\n}\n")
+
+	_, err := ParseConfig(repositorySetPath)
+	assert.ErrorContains(s.T(), err, "included configurations cannot include other configurations")
+}
+
+func (s *ConfigTestSuite) TestParseConfigMergesIncludedInputs() {
+	s.writeFile("/repo/projects/terragrunt.hcl", "inputs = {\n  a = 1\n  b = 1\n}\n")
+	s.writeFile(repositorySetPath, "include {\n  path = find_in_parent_folders()\n}\n\ninputs = {\n  b = 2\n}\n")
+
+	config, err := ParseConfig(repositorySetPath)
+	require.NoError(s.T(), err)
+	assert.True(s.T(), config.Inputs["a"].Value.Equals(cty.NumberIntVal(1)).True())
+	assert.Equal(s.T(), "/repo/projects/terragrunt.hcl", config.Inputs["a"].Range.Filename)
+	assert.True(s.T(), config.Inputs["b"].Value.Equals(cty.NumberIntVal(2)).True())
+}
+
+const fullRepositorySetConfig = `inputs = {
+  private_repositories = {
+    app = {
+      description                          = "App"
+      default_branch                       = "main"
+      repository_team_permissions_override = { devs = "push" }
+      user_permissions                     = { octocat = "admin" }
+      protected_branches                   = ["main"]
+      advance_security                     = true
+      has_vulnerability_alerts             = true
+      topics                               = ["go"]
+      homepage                             = "https://example.com"
+      delete_head_on_merge                 = true
+      requires_web_commit_signing          = true
+      dependabot_security_updates          = true
+      allow_auto_merge                     = true
+      allow_update_branch                  = true
+      organization_action_secrets          = ["ORG_ACTION"]
+      organization_codespace_secrets       = ["ORG_CODESPACE"]
+      organization_dependabot_secrets      = ["ORG_DEPENDABOT"]
+      action_secrets                       = { ACTION = "encrypted" }
+      codespace_secrets                    = { CODESPACE = "encrypted" }
+      dependabot_secrets                   = { DEPENDABOT = "encrypted" }
+      environments = {
+        production = {
+          action_secrets = { DEPLOY = "encrypted" }
+        }
+      }
+      template_repository = {
+        owner                = "octo-org"
+        repository           = "template"
+        include_all_branches = true
+      }
+      license_template = "mit"
+    }
+  }
+  public_repositories = {}
+  default_repository_team_permissions = {
+    maintainers = "maintain"
+  }
+}
+`
+
+func (s *ConfigTestSuite) TestRepositorySetRoundTrip() {
+	s.writeFile(repositorySetPath, fullRepositorySetConfig)
+	hclFile := HCLFile{Path: repositorySetPath}
+	inputs, err := hclFile.GetInputsFromFile()
+	require.NoError(s.T(), err)
+
+	app := inputs.PrivateRepositories["app"]
+	input := app.GetRepositoryInput()
+	assert.Equal(s.T(), githubfoundations.RepositoryInput{
+		Name:                              "app",
+		Description:                       "App",
+		DefaultBranch:                     "main",
+		RepositoryTeamPermissionsOverride: map[string]string{"devs": "push"},
+		ProtectedBranches:                 []string{"main"},
+		AdvanceSecurity:                   true,
+		HasVulnerabilityAlerts:            true,
+		Topics:                            []string{"go"},
+		Homepage:                          "https://example.com",
+		DeleteHeadBranchOnMerge:           true,
+		RequiresWebCommitSignOff:          true,
+		DependabotSecurityUpdates:         true,
+		AllowAutoMerge:                    true,
+		AllowUpdateBranch:                 true,
+		OrganizationActionSecrets:         []string{"ORG_ACTION"},
+		OrganizationCodespaceSecrets:      []string{"ORG_CODESPACE"},
+		OrganizationDependabotSecrets:     []string{"ORG_DEPENDABOT"},
+		ActionSecrets:                     map[string]string{"ACTION": "encrypted"},
+		CodespaceSecrets:                  map[string]string{"CODESPACE": "encrypted"},
+		DependabotSecrets:                 map[string]string{"DEPENDABOT": "encrypted"},
+		Environments: map[string]githubfoundations.EnvironmentInputs{
+			"production": {ActionSecrets: map[string]string{"DEPLOY": "encrypted"}},
+		},
+		TemplateRepository: &githubfoundations.TemplateRepositoryInputs{
+			Owner:              "octo-org",
+			Repository:         "template",
+			IncludeAllBranches: true,
+		},
+		LicenseTemplate: "mit",
+		UserPermissions: map[string]string{"octocat": "admin"},
+		SetInputs: []string{
+			"action_secrets", "advance_security", "allow_auto_merge", "allow_update_branch",
+			"codespace_secrets", "default_branch", "delete_head_on_merge", "dependabot_secrets",
+			"dependabot_security_updates", "description", "environments", "has_vulnerability_alerts",
+			"homepage", "license_template", "organization_action_secrets", "organization_codespace_secrets",
+			"organization_dependabot_secrets", "protected_branches", "repository_team_permissions_override",
+			"requires_web_commit_signing", "template_repository", "topics", "user_permissions",
+		},
+	}, input)
+
+	// Writing the repository set back and reading it again loses nothing
+	repoSet := githubfoundations.RepositorySetInput{
+		PrivateRepositories:              []*githubfoundations.RepositoryInput{&input},
+		DefaultRepositoryTeamPermissions: inputs.DefaultRepositoryTeamPermissions,
+	}
+	file := hclwrite.NewEmptyFile()
+	repoSet.WriteHCL(file)
+	s.writeFile(repositorySetPath, string(file.Bytes()))
+
+	written, err := hclFile.GetInputsFromFile()
+	require.NoError(s.T(), err)
+	for _, repos := range []map[string]status.Repository{inputs.PrivateRepositories, written.PrivateRepositories} {
+		for name, repo := range repos {
+			repo.Range = hcl.Range{}
+			repos[name] = repo
+		}
+	}
+	assert.Equal(s.T(), inputs, written)
+}
+
+const minimalRepositorySetConfig = `inputs = {
+  private_repositories = {
+    app = {
+      description                          = "App"
+      default_branch                       = "main"
+      repository_team_permissions_override = {}
+      advance_security                     = false
+      has_vulnerability_alerts             = true
+      topics                               = []
+      homepage                             = ""
+      delete_head_on_merge                 = true
+      allow_auto_merge                     = true
+      dependabot_security_updates          = true
+    }
+    unprotected = {
+      description                          = "Unprotected"
+      default_branch                       = "main"
+      repository_team_permissions_override = {}
+      protected_branches                   = []
+      advance_security                     = false
+      has_vulnerability_alerts             = true
+      topics                               = []
+      homepage                             = ""
+      delete_head_on_merge                 = true
+      allow_auto_merge                     = true
+      dependabot_security_updates          = true
+    }
+  }
+  public_repositories = {}
+}
+`
+
+func (s *ConfigTestSuite) TestMinimalRepositorySetRoundTrip() {
+	s.writeFile(repositorySetPath, minimalRepositorySetConfig)
+	hclFile := HCLFile{Path: repositorySetPath}
+	inputs, err := hclFile.GetInputsFromFile()
+	require.NoError(s.T(), err)
+
+	repoSet := githubfoundations.RepositorySetInput{}
+	for _, name := range []string{"app", "unprotected"} {
+		repo := inputs.PrivateRepositories[name]
+		input := repo.GetRepositoryInput()
+		repoSet.PrivateRepositories = append(repoSet.PrivateRepositories, &input)
+	}
+	file := hclwrite.NewEmptyFile()
+	repoSet.WriteHCL(file)
+	s.writeFile(repositorySetPath, string(file.Bytes()))
+
+	// The inputs the repository set does not give are not written with their zero value
+	written, err := hclFile.GetInputsFromFile()
+	require.NoError(s.T(), err)
+	assert.Nil(s.T(), written.PrivateRepositories["app"].ProtectedBranches)
+	assert.NotContains(s.T(), written.PrivateRepositories["app"].Inputs, "protected_branches")
+	assert.NotContains(s.T(), written.PrivateRepositories["app"].Inputs, "requires_web_commit_signing")
+	assert.Equal(s.T(), []string{}, written.PrivateRepositories["unprotected"].ProtectedBranches)
+	for _, repos := range []map[string]status.Repository{inputs.PrivateRepositories, written.PrivateRepositories} {
+		for name, repo := range repos {
+			repo.Range = hcl.Range{}
+			repos[name] = repo
+		}
+	}
+	assert.Equal(s.T(), inputs, written)
+}
+
+func (s *ConfigTestSuite) TestGetTeamInputsFromFile() {
+	teamSetPath := "/repo/projects/project/octo-org/teams/terragrunt.hcl"
+	s.writeFile(teamSetPath, `inputs = {
+  teams = {
+    "admins" = {
+      description = "Admins"
+      privacy     = "closed"
+      members     = ["octocat"]
+      maintainers = ["hubot"]
+    }
+    "developers" = {
+      description    = "Developers"
+      privacy        = "secret"
+      members        = []
+      maintainers    = ["Octocat"]
+      parent_team_id = 1234567
+    }
+  }
+}
+`)
+
+	hclFile := HCLFile{Path: teamSetPath}
+	inputs, err := hclFile.GetTeamInputsFromFile()
+	require.NoError(s.T(), err)
+
+	require.Len(s.T(), inputs.Teams, 2)
+	admins := inputs.Teams["admins"]
+	assert.Equal(s.T(), "admins", admins.Name)
+	assert.Equal(s.T(), "closed", admins.Privacy)
+	assert.Equal(s.T(), []string{"octocat"}, admins.Members)
+	assert.Equal(s.T(), []string{"hubot"}, admins.Maintainers)
+	assert.Equal(s.T(), 3, admins.Range.Start.Line)
+
+	developers := inputs.Teams["developers"]
+	input := developers.GetTeamInput()
+	assert.Equal(s.T(), "1234567", input.ParentId)
+	assert.True(s.T(), input.HasMember("octocat"))
+	assert.False(s.T(), input.HasMember("hubot"))
+}
+
+func (s *ConfigTestSuite) TestGetTeamInputsFromFileUnknownInput() {
+	s.writeFile(repositorySetPath, "inputs = {\n  teams = {}\n  private_repositories = {}\n}\n")
+
+	hclFile := HCLFile{Path: repositorySetPath}
+	_, err := hclFile.GetTeamInputsFromFile()
+	assert.EqualError(s.T(), err, repositorySetPath+":3: unknown input private_repositories")
+}
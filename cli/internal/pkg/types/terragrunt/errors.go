@@ -0,0 +1,107 @@
+package terragrunt
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// ConfigError is an error in a Terragrunt configuration file, at a line of the file when
+// it is known
+type ConfigError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigErrors are the errors found in one or more configuration files
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e ConfigErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Add appends the errors of a configuration file. Errors that are not configuration errors
+// are attributed to the file.
+func (e ConfigErrors) Add(path string, err error) ConfigErrors {
+	var configErrs ConfigErrors
+	var configErr *ConfigError
+	switch {
+	case err == nil:
+		return e
+	case errors.As(err, &configErrs):
+		return append(e, configErrs...)
+	case errors.As(err, &configErr):
+		return append(e, configErr)
+	default:
+		return append(e, &ConfigError{Path: path, Err: err})
+	}
+}
+
+// OrNil returns the errors as an error, or nil when there are none
+func (e ConfigErrors) OrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Sort the errors by file, then by line
+func (e ConfigErrors) sort() {
+	sort.SliceStable(e, func(i, j int) bool {
+		if e[i].Path != e[j].Path {
+			return e[i].Path < e[j].Path
+		}
+		return e[i].Line < e[j].Line
+	})
+}
+
+func rangeError(r hcl.Range, format string, args ...any) *ConfigError {
+	return &ConfigError{Path: r.Filename, Line: r.Start.Line, Err: fmt.Errorf(format, args...)}
+}
+
+// The error diagnostics, with the location of their subject
+func diagnosticsError(path string, diags hcl.Diagnostics) error {
+	var errs ConfigErrors
+	for _, diag := range diags.Errs() {
+		err := &ConfigError{Path: path, Err: errors.New(diag.Error())}
+		if d, ok := diag.(*hcl.Diagnostic); ok {
+			err.Err = errors.New(d.Summary)
+			if d.Detail != "" {
+				err.Err = fmt.Errorf("%s; %s", d.Summary, d.Detail)
+			}
+			if d.Subject != nil {
+				err.Path = d.Subject.Filename
+				err.Line = d.Subject.Start.Line
+			}
+		}
+		errs = append(errs, err)
+	}
+	return errs.OrNil()
+}
@@ -8,15 +8,12 @@ import (
 	"gh_foundations/internal/pkg/types/terraform_state"
 	v1_2 "gh_foundations/internal/pkg/types/terraform_state/v1.2"
 	"io"
-	"log"
 	"os/exec"
 	"path"
-	"regexp"
-	"strings"
+	"sort"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/afero"
-	"github.com/spf13/viper"
 	"github.com/tidwall/gjson"
 )
 
@@ -47,182 +44,154 @@ type HCLFile struct {
 	Path string
 }
 
-func getRepository(repo map[string]interface{}) (status.Repository, error) {
-	var repository status.Repository
-
-	err := mapstructure.Decode(repo, &repository)
-	if err != nil {
-		log.Fatalf("Error in getInputsFromFile mapstructure.Decode: %s", err)
-		return repository, err
-	}
-
-	return repository, nil
-
-}
-
-
-// Given a repository map, returned by Viper, return a map of status.Repository
-func getRepositoryMap(repoList []map[string]interface{}) (map[string]status.Repository, error) {
+// Decode the repositories of a repository set input, with the range of each repository.
+// The repositories that cannot be decoded are left out and reported in the errors.
+func getRepositoryMap(input Input) (map[string]status.Repository, error) {
 	repos := make(map[string]status.Repository)
 
-	for name, r := range repoList[0] {
-		details := r.([]map[string]interface{})
-		d := details[0]
-		repo, err := getRepository(d)
-		if err != nil {
-			log.Fatalf("Error in getRepositoryMap: %s", err)
-			return repos, err
+	values, ok := toGo(input.Value).(map[string]any)
+	if !ok {
+		return repos, rangeError(input.Range, "expected an object of repositories")
+	}
+	var errs ConfigErrors
+	for name, value := range values {
+		r, ok := input.Items[name]
+		if !ok {
+			r = input.Range
+		}
+		var repository status.Repository
+		if err := mapstructure.Decode(value, &repository); err != nil {
+			errs = append(errs, rangeError(r, "invalid repository %s: %w", name, err))
+			continue
 		}
-		repos[name] = repo
+		repository.Name = name
+		repository.Range = r
+		repository.Inputs = inputNames(value)
+		repos[name] = repository
 	}
-	return repos, nil
+	errs.sort()
+	return repos, errs.OrNil()
 }
 
-// Return the locals block from the HCL file as a slice of string slices
-func getLocalsBlock(contents string) [][]string {
-	// The locals are in the form of locals = { key = value }
+// The sorted names of the attributes of an object value
+func inputNames(value any) []string {
+	attributes, _ := value.(map[string]any)
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-	// Use regex to find the locals block
-	lre := regexp.MustCompile(`locals\s*{\n*((.*[^}])\n)+}`)
-	locals := lre.FindString(contents)
+// Given an HCL file, return the inputs. The inputs that cannot be read are left out and
+// reported in the errors, as ConfigErrors.
+func (h *HCLFile) GetInputsFromFile() (status.Inputs, error) {
+	var inputs status.Inputs
 
-	if locals == "" {
-		fmt.Printf("locals not found")
-		return make([][]string, 0)
+	config, err := ParseConfig(h.Path)
+	if err != nil {
+		return inputs, err
 	}
 
-	// Use regex to find the key-value pairs in the locals block
-	kvre := regexp.MustCompile(`(.*[^=])=(.*(?:(:?\n.*[^\]])*])*)`)
-	matches := kvre.FindAllStringSubmatch(locals, -1)
-
-	// Clean up the matches a little
-	for i, match := range matches {
-		matches[i][1] = strings.Trim(match[1], " \"")
-		matches[i][2] = strings.Trim(match[2], " \"")
+	var errs ConfigErrors
+	for key, input := range config.Inputs {
+		switch key {
+		case "private_repositories":
+			repos, err := getRepositoryMap(input)
+			errs = errs.Add(h.Path, err)
+			inputs.PrivateRepositories = repos
+		case "public_repositories":
+			repos, err := getRepositoryMap(input)
+			errs = errs.Add(h.Path, err)
+			inputs.PublicRepositories = repos
+		case "default_repository_team_permissions":
+			permissions := make(map[string]string)
+			if err := mapstructure.Decode(toGo(input.Value), &permissions); err != nil {
+				errs = append(errs, rangeError(input.Range, "invalid default_repository_team_permissions: %w", err))
+				continue
+			}
+			inputs.DefaultRepositoryTeamPermissions = permissions
+		default:
+			errs = append(errs, rangeError(input.Range, "unknown input %s", key))
+		}
 	}
 
-	return matches
+	errs.sort()
+	return inputs, errs.OrNil()
 }
 
+// Given a team set HCL file, return the inputs. The teams that cannot be read are left out
+// and reported in the errors, as ConfigErrors.
+func (h *HCLFile) GetTeamInputsFromFile() (status.TeamInputs, error) {
+	var inputs status.TeamInputs
 
-// The locals are in the form of locals = { key = value }
-// Then, they are referred to as local.key in the configuration
-// This function replaces the locals with their values
-func replaceLocals(contents string) string {
-	matches := getLocalsBlock(contents)
-
-	// Replace the locals with their values
-	for _, match := range matches {
-		key := strings.Trim(match[1], " ")
-		value := strings.Trim(match[2], " ")
-
-		contents = strings.ReplaceAll(contents, key, value)
+	config, err := ParseConfig(h.Path)
+	if err != nil {
+		return inputs, err
+	}
 
+	var errs ConfigErrors
+	for key, input := range config.Inputs {
+		switch key {
+		case "teams":
+			teams, err := getTeamMap(input)
+			errs = errs.Add(h.Path, err)
+			inputs.Teams = teams
+		default:
+			errs = append(errs, rangeError(input.Range, "unknown input %s", key))
+		}
 	}
 
-    return contents
+	errs.sort()
+	return inputs, errs.OrNil()
 }
 
-// Given an HCL file, return the inputs
-func (h *HCLFile) GetInputsFromFile() (status.Inputs, error) {
-
-	var inputs status.Inputs
+// Decode the teams of a team set input, with the range of each team
+func getTeamMap(input Input) (map[string]status.Team, error) {
+	teams := make(map[string]status.Team)
 
-	viper.SetConfigType("hcl")
-	viper.SetConfigFile(h.Path)
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// Config file not found;
-			log.Fatalf(`GetInputsFromFile: config file not found: %s`, h.Path)
-			return inputs, err
-		} else if _, ok := err.(viper.ConfigParseError); ok {
-			// the viper library can't parse the "locals". Here's a workaround
-			// read in the file contents and replace the locals with their values
-			// then write the file to a temporary location and read it in
-			// again
-
-			// read in the file contents
-			contents, err := afero.ReadFile(fs, h.Path)
-			if err != nil {
-				log.Fatalf(`GetInputsFromFile: unable to read config file: %s`, h.Path)
-				return inputs, err
-			}
-			// replace the locals with their values
-			contents = []byte(replaceLocals(string(contents)))
-			// write the file to a temporary location and read it in again
-			tempPath := "/tmp/" + path.Base(h.Path)
-			err = afero.WriteFile(fs, tempPath, contents, 0644)
-			if err != nil {
-				log.Fatalf(`GetInputsFromFile: unable to write config file: %s`, h.Path)
-				return inputs, err
-			}
-			viper.SetConfigFile(tempPath)
-			viper.ReadInConfig()
-		} else {
-			// Config file was found but another error was produced
-			log.Fatalf(`GetInputsFromFile: config file found but another error was produced: %s`, h.Path)
-			return inputs, err
-		}
+	values, ok := toGo(input.Value).(map[string]any)
+	if !ok {
+		return teams, rangeError(input.Range, "expected an object of teams")
 	}
-
-	raw := viper.Get("inputs").([]map[string]interface{})
-	for key, input := range raw[0] {
-		switch key {
-			case "private_repositories":
-				repoList := input.([]map[string]interface{})
-				repos, err := getRepositoryMap(repoList)
-				if err != nil {
-					log.Fatalf("Error in getRepositoryMap: %s", err)
-					return inputs, err
-				}
-				inputs.PrivateRepositories = repos
-			case "public_repositories":
-				repoList := input.([]map[string]interface{})
-				repos, err := getRepositoryMap(repoList)
-				if err != nil {
-					log.Fatalf("Error in getRepositoryMap: %s", err)
-					return inputs, err
-				}
-				inputs.PublicRepositories = repos
-			case "default_repository_team_permissions":
-				permissions := make(map[string]string)
-				inputArr := input.([]map[string]interface{})
-				drtpsArr := inputArr[0]
-				for permission, value := range drtpsArr {
-					permissions[permission] = value.(string)
-				}
-				inputs.DefaultRepositoryTeamPermissions = permissions
-			default:
-				log.Fatalf("Unknown input: %s", key)
-				return inputs, nil
+	var errs ConfigErrors
+	for name, value := range values {
+		r, ok := input.Items[name]
+		if !ok {
+			r = input.Range
+		}
+		var team status.Team
+		// Parent team ids may be written as numbers
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{WeaklyTypedInput: true, Result: &team})
+		if err != nil {
+			return teams, err
 		}
+		if err := decoder.Decode(value); err != nil {
+			errs = append(errs, rangeError(r, "invalid team %s: %w", name, err))
+			continue
+		}
+		team.Name = name
+		team.Range = r
+		teams[name] = team
 	}
-
-	return inputs, nil
+	errs.sort()
+	return teams, errs.OrNil()
 }
 
-
-// Given the string content of an HCL file, return a map of locals
-func (h *HCLFile) GetLocalsMap() map[string]string {
+// Given an HCL file, return the locals whose values are strings
+func (h *HCLFile) GetLocalsMap() (map[string]string, error) {
 
 	// If the path is not set, return an empty map
 	if h.Path == "" {
-		return make(map[string]string)
+		return make(map[string]string), nil
 	}
 
-	// If the path is set, read the file and return the locals
-	content, err := afero.ReadFile(fs, h.Path)
+	config, err := ParseConfig(h.Path)
 	if err != nil {
-		log.Fatalf(`GetLocalsMap: unable to read config file: %s`, h.Path)
-		return make(map[string]string)
-	}
-
-	macthes := getLocalsBlock(string(content))
-	locals := make(map[string]string)
-	for _, match := range macthes {
-		locals[match[1]] = match[2]
+		return make(map[string]string), err
 	}
-	return locals
+	return config.stringLocals(), nil
 }
 
 func NewTerragruntPlanFile(name string, modulePath string, moduleDir string, outputFilePath string) (*PlanFile, error) {